@@ -23,6 +23,7 @@ go get -u google.golang.org/grpc \
         github.com/jessevdk/go-flags \
         github.com/stretchr/testify/mock \
         github.com/btcsuite/btcd/btcec \
+        github.com/mattn/go-sqlite3 \
         gopkg.in/yaml.v2
 
 go install github.com/golang/mock/mockgen
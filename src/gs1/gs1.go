@@ -0,0 +1,88 @@
+// Package gs1 validates GS1 identification keys such as the Global Trade Item
+// Number. It is shared by the mdata client and transaction processor so both
+// reject the same codes.
+package gs1
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GtinLength is the length of a GTIN-14, the only form stored on chain
+const GtinLength = 14
+
+// Lengths of the GTIN-8, GTIN-12 (UPC-A), GTIN-13 (EAN-13) and GTIN-14 forms
+var gtinLengths = []int{8, 12, 13, GtinLength}
+
+// ValidateGtin returns an error describing why code is not a GTIN-8, GTIN-12,
+// GTIN-13 or GTIN-14 with a correct GS1 mod-10 check digit, or nil if it is one.
+func ValidateGtin(code string) error {
+	_, err := NormalizeGtin(code)
+	return err
+}
+
+// NormalizeGtin validates code and returns it as a GTIN-14, left-padding the
+// shorter forms with zeros. Padding does not change the check digit, so
+// 012345678905 and 00012345678905 normalize to the same GTIN-14.
+func NormalizeGtin(code string) (string, error) {
+	if code == "" {
+		return "", errors.New("Gtin-14 is required")
+	}
+	if !isGtinLength(len(code)) || !isDigits(code) {
+		return "", fmt.Errorf("Invalid GTIN '%v': a GTIN is 8, 12, 13 or 14 digits", code)
+	}
+
+	last := len(code) - 1
+	expected := CheckDigit(code[:last])
+	if int(code[last]-'0') != expected {
+		return "", fmt.Errorf("Invalid GTIN '%v': check digit is %c, expected %d", code, code[last], expected)
+	}
+	return strings.Repeat("0", GtinLength-len(code)) + code, nil
+}
+
+// NormalizeStoredGtin returns code as the GTIN-14 a product may be stored
+// under. Family version 1.0 stored any 14 digits without checking the GS1
+// check digit, so a GTIN-14 is accepted whatever its check digit; the shorter
+// forms are validated and padded by NormalizeGtin. Use it to read or change
+// existing products, and NormalizeGtin to create them.
+func NormalizeStoredGtin(code string) (string, error) {
+	if len(code) == GtinLength && isDigits(code) {
+		return code, nil
+	}
+	return NormalizeGtin(code)
+}
+
+// CheckDigit computes the GS1 mod-10 check digit for digits, the GTIN without
+// its final check digit. Counting from the right, digits are weighted 3, 1,
+// 3, 1 and so on; the check digit brings the weighted sum up to a multiple of
+// ten. digits must contain only the characters 0-9.
+func CheckDigit(digits string) int {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return (10 - sum%10) % 10
+}
+
+func isGtinLength(length int) bool {
+	for _, l := range gtinLengths {
+		if length == l {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if str[i] < '0' || str[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package gs1
+
+import (
+	"testing"
+)
+
+func TestValidateGtinGtin(t *testing.T) {
+	tests := map[string]struct {
+		in    string
+		valid bool
+	}{
+		"empty":           {in: "", valid: false},
+		"valid":           {in: "00012345600012", valid: true},
+		"validCheckZero":  {in: "00012345678905", valid: true},
+		"badCheckDigit":   {in: "00012345600013", valid: false},
+		"gtin8":           {in: "96385074", valid: true},
+		"gtin12":          {in: "012345678905", valid: true},
+		"gtin13":          {in: "4006381333931", valid: true},
+		"gtin12BadCheck":  {in: "012345678900", valid: false},
+		"nineDigits":      {in: "963850741", valid: false},
+		"tooLong":         {in: "000012345600012", valid: false},
+		"letters":         {in: "0001234560001A", valid: false},
+		"sign":            {in: "+0012345600012", valid: false},
+		"overflowsInt32":  {in: "99999999999997", valid: true},
+		"overflowBadSign": {in: "99999999999990", valid: false},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		err := ValidateGtin(test.in)
+		if (err == nil) != test.valid {
+			t.Errorf("Test Case Failure %v \n ValidateGtin(%v) => GOT %v, WANT valid=%v", name, test.in, err, test.valid)
+		}
+	}
+}
+
+func TestValidateGtinNamesExpectedDigit(t *testing.T) {
+	err := ValidateGtin("00012345600013")
+	expected := "Invalid GTIN '00012345600013': check digit is 3, expected 2"
+	if err == nil || err.Error() != expected {
+		t.Errorf("ValidateGtin(00012345600013) => GOT %v, WANT %v", err, expected)
+	}
+}
+
+func TestNormalizeGtin(t *testing.T) {
+	tests := map[string]string{
+		"96385074":       "00000096385074",
+		"012345678905":   "00012345678905",
+		"4006381333931":  "04006381333931",
+		"00012345678905": "00012345678905",
+	}
+	for in, expected := range tests {
+		actual, err := NormalizeGtin(in)
+		if err != nil || actual != expected {
+			t.Errorf("NormalizeGtin(%v) => GOT %v, %v, WANT %v", in, actual, err, expected)
+		}
+	}
+
+	_, err := NormalizeGtin("012345678900")
+	expected := "Invalid GTIN '012345678900': check digit is 0, expected 5"
+	if err == nil || err.Error() != expected {
+		t.Errorf("NormalizeGtin(012345678900) => GOT %v, WANT %v", err, expected)
+	}
+}
+
+func TestNormalizeStoredGtin(t *testing.T) {
+	tests := map[string]struct {
+		in       string
+		expected string
+		valid    bool
+	}{
+		"valid":          {in: "00012345600012", expected: "00012345600012", valid: true},
+		"badCheckDigit":  {in: "00012345600013", expected: "00012345600013", valid: true},
+		"gtin12":         {in: "012345678905", expected: "00012345678905", valid: true},
+		"gtin12BadCheck": {in: "012345678900", valid: false},
+		"letters":        {in: "0001234560001A", valid: false},
+		"tooLong":        {in: "000012345600012", valid: false},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		actual, err := NormalizeStoredGtin(test.in)
+		if (err == nil) != test.valid || actual != test.expected {
+			t.Errorf("Test Case Failure %v \n NormalizeStoredGtin(%v) => GOT %v, %v, WANT %v, valid=%v",
+				name, test.in, actual, err, test.expected, test.valid)
+		}
+	}
+}
+
+func TestCheckDigit(t *testing.T) {
+	tests := map[string]int{
+		"0001234560001": 2,
+		"0001234567890": 5,
+		"9999999999999": 7,
+		"0000000000000": 0,
+	}
+	for digits, expected := range tests {
+		if actual := CheckDigit(digits); actual != expected {
+			t.Errorf("CheckDigit(%v) => GOT %v, WANT %v", digits, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package lifecycle describes the states a product moves through. The states
+// and transitions are read from the mdata.product.states Sawtooth setting, so
+// the mdata client and transaction processor share the parsing here.
+package lifecycle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	StateActive       = "ACTIVE"
+	StateInactive     = "INACTIVE"
+	StateDiscontinued = "DISCONTINUED"
+	// StateDeleted is the target of a delete. Products reaching it are removed
+	// from state rather than stored.
+	StateDeleted = "DELETED"
+)
+
+// SettingKey is the Sawtooth setting holding the lifecycle, in the form read
+// by Parse
+const SettingKey = "mdata.product.states"
+
+// Lifecycle is the state machine a product moves through. A product is
+// created in InitialState and Transitions lists the states each state can
+// move to. A state without transitions is terminal: only an admin of the
+// organization owning the product can move it out.
+type Lifecycle struct {
+	InitialState string
+	Transitions  map[string][]string
+}
+
+// Default is the lifecycle used when the setting is not set. Its setting
+// value is "ACTIVE:INACTIVE;INACTIVE:ACTIVE,DISCONTINUED,DELETED;DISCONTINUED".
+var Default = &Lifecycle{
+	InitialState: StateActive,
+	Transitions: map[string][]string{
+		StateActive:       {StateInactive},
+		StateInactive:     {StateActive, StateDiscontinued, StateDeleted},
+		StateDiscontinued: {},
+	},
+}
+
+// Parse reads a lifecycle from its setting value: states separated by ";",
+// each followed by ":" and the comma separated states it can move to. The
+// first state is the initial state, and a state without ":" is terminal.
+// DELETED may be a target, allowing delete, but not a state.
+func Parse(value string) (*Lifecycle, error) {
+	l := &Lifecycle{Transitions: make(map[string][]string)}
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		state := strings.TrimSpace(parts[0])
+		if err := validateName(state); err != nil {
+			return nil, err
+		}
+		if state == StateDeleted {
+			return nil, fmt.Errorf("%v is the target of a delete and cannot be a state", StateDeleted)
+		}
+		if _, ok := l.Transitions[state]; ok {
+			return nil, fmt.Errorf("State %v is listed twice", state)
+		}
+
+		targets := []string{}
+		if len(parts) == 2 && strings.TrimSpace(parts[1]) != "" {
+			for _, target := range strings.Split(parts[1], ",") {
+				target = strings.TrimSpace(target)
+				if err := validateName(target); err != nil {
+					return nil, err
+				}
+				targets = append(targets, target)
+			}
+		}
+		l.Transitions[state] = targets
+		if l.InitialState == "" {
+			l.InitialState = state
+		}
+	}
+
+	if l.InitialState == "" {
+		return nil, errors.New("A lifecycle requires at least one state")
+	}
+	for state, targets := range l.Transitions {
+		for _, target := range targets {
+			if target != StateDeleted && !l.IsState(target) {
+				return nil, fmt.Errorf("State %v moves to %v, which is not a state", state, target)
+			}
+		}
+	}
+	return l, nil
+}
+
+// String formats the lifecycle as the setting value read by Parse
+func (self *Lifecycle) String() string {
+	entries := []string{self.entry(self.InitialState)}
+	for _, state := range self.States() {
+		if state != self.InitialState {
+			entries = append(entries, self.entry(state))
+		}
+	}
+	return strings.Join(entries, ";")
+}
+
+func (self *Lifecycle) entry(state string) string {
+	if self.IsTerminal(state) {
+		return state
+	}
+	return state + ":" + strings.Join(self.Transitions[state], ",")
+}
+
+// States returns the states of the lifecycle in sorted order
+func (self *Lifecycle) States() []string {
+	states := make([]string, 0, len(self.Transitions))
+	for state := range self.Transitions {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	return states
+}
+
+// IsState reports whether state is one of the lifecycle's states
+func (self *Lifecycle) IsState(state string) bool {
+	_, ok := self.Transitions[state]
+	return ok
+}
+
+// IsTerminal reports whether no transition leaves state
+func (self *Lifecycle) IsTerminal(state string) bool {
+	return len(self.Transitions[state]) == 0
+}
+
+// ValidateState returns an error naming the lifecycle's states unless state
+// is one of them
+func (self *Lifecycle) ValidateState(state string) error {
+	if !self.IsState(state) {
+		return fmt.Errorf("Invalid state (state must be one of %v), GOT: %v", strings.Join(self.States(), ", "), state)
+	}
+	return nil
+}
+
+// CheckTransition returns an error unless a product in state from may move
+// to state to. Staying in the same state is always allowed. admin reports
+// whether the signer is an admin of the organization owning the product, who
+// may move it out of a terminal state.
+func (self *Lifecycle) CheckTransition(from string, to string, admin bool) error {
+	if from == to {
+		return nil
+	}
+	for _, state := range self.Transitions[from] {
+		if state == to {
+			return nil
+		}
+	}
+	if self.IsTerminal(from) {
+		if admin && (self.IsState(to) || to == StateDeleted) {
+			return nil
+		}
+		return fmt.Errorf("Product is %v, a terminal state only an admin of the owning organization can move it out of", from)
+	}
+	return fmt.Errorf("Invalid state transition from %v to %v: %v products can only move to %v",
+		from, to, from, strings.Join(self.Transitions[from], ", "))
+}
+
+// ValidName reports whether name can be a state: upper case letters, digits
+// and underscores, starting with a letter
+func ValidName(name string) bool {
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func validateName(name string) error {
+	if !ValidName(name) {
+		return fmt.Errorf("Invalid state name '%v': use upper case letters, digits and underscores", name)
+	}
+	return nil
+}
+
+// SettingAddress returns the state address of the Sawtooth setting key: the
+// settings namespace followed by the hashes of the first three dot separated
+// parts of the key and of the rest, each cut to 16 characters.
+func SettingAddress(key string) string {
+	parts := strings.SplitN(key, ".", 4)
+	for len(parts) < 4 {
+		parts = append(parts, "")
+	}
+	address := "000000"
+	for _, part := range parts {
+		hash := sha256.Sum256([]byte(part))
+		address += hex.EncodeToString(hash[:])[:16]
+	}
+	return address
+}
@@ -0,0 +1,104 @@
+package lifecycle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTransition(t *testing.T) {
+
+	tests := map[string]struct {
+		from  string
+		to    string
+		admin bool
+		valid bool
+	}{
+		"deactivate":                {from: StateActive, to: StateInactive, valid: true},
+		"reactivate":                {from: StateInactive, to: StateActive, valid: true},
+		"discontinue":               {from: StateInactive, to: StateDiscontinued, valid: true},
+		"deleteInactive":            {from: StateInactive, to: StateDeleted, valid: true},
+		"sameState":                 {from: StateActive, to: StateActive, valid: true},
+		"discontinueActive":         {from: StateActive, to: StateDiscontinued, valid: false},
+		"deleteActive":              {from: StateActive, to: StateDeleted, valid: false},
+		"reactivateDiscontinued":    {from: StateDiscontinued, to: StateActive, valid: false},
+		"deleteDiscontinued":        {from: StateDiscontinued, to: StateDeleted, valid: false},
+		"adminReactivates":          {from: StateDiscontinued, to: StateActive, admin: true, valid: true},
+		"adminDeletesDiscontinued":  {from: StateDiscontinued, to: StateDeleted, admin: true, valid: true},
+		"adminUnknownState":         {from: StateDiscontinued, to: "RETIRED", admin: true, valid: false},
+		"adminOutsideTerminalState": {from: StateActive, to: StateDeleted, admin: true, valid: false},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+
+		err := Default.CheckTransition(test.from, test.to, test.admin)
+		if test.valid {
+			assert.Nil(t, err)
+		} else {
+			assert.NotNil(t, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+
+	tests := map[string]struct {
+		in  string
+		out *Lifecycle
+	}{
+		"default": {
+			in:  "ACTIVE:INACTIVE;INACTIVE:ACTIVE,DISCONTINUED,DELETED;DISCONTINUED",
+			out: Default,
+		},
+		"spacesAndEmptyTerminal": {
+			in: " DRAFT : PUBLISHED , DELETED ; PUBLISHED : RETIRED ; RETIRED: ;",
+			out: &Lifecycle{
+				InitialState: "DRAFT",
+				Transitions: map[string][]string{
+					"DRAFT":     {"PUBLISHED", "DELETED"},
+					"PUBLISHED": {"RETIRED"},
+					"RETIRED":   {},
+				},
+			},
+		},
+		"empty":          {in: " ; ", out: nil},
+		"unknownTarget":  {in: "ACTIVE:INACTIVE", out: nil},
+		"deletedState":   {in: "ACTIVE:DELETED;DELETED", out: nil},
+		"duplicateState": {in: "ACTIVE;ACTIVE", out: nil},
+		"lowerCase":      {in: "active", out: nil},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+
+		l, err := Parse(test.in)
+		assert.Equal(t, test.out, l)
+		assert.Equal(t, test.out == nil, err != nil)
+	}
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "ACTIVE:INACTIVE;DISCONTINUED;INACTIVE:ACTIVE,DISCONTINUED,DELETED", Default.String())
+
+	l, err := Parse(Default.String())
+	assert.Nil(t, err)
+	assert.Equal(t, Default, l)
+}
+
+func TestStates(t *testing.T) {
+	assert.Equal(t, []string{StateActive, StateDiscontinued, StateInactive}, Default.States())
+	assert.True(t, Default.IsTerminal(StateDiscontinued))
+	assert.False(t, Default.IsTerminal(StateInactive))
+	assert.False(t, Default.IsState(StateDeleted))
+	assert.Nil(t, Default.ValidateState(StateInactive))
+	assert.NotNil(t, Default.ValidateState("RETIRED"))
+}
+
+func TestSettingAddress(t *testing.T) {
+	// Address of a setting used by every Sawtooth network
+	assert.Equal(t,
+		"000000a87cb5eafdcca6a8cde0fb0dec1400c5ab274474a6aa82c12840f169a04216b7",
+		SettingAddress("sawtooth.settings.vote.authorized_keys"))
+	assert.Len(t, SettingAddress(SettingKey), 70)
+}
@@ -0,0 +1,180 @@
+// Package api serves the products and history in the mdata index as JSON
+// over HTTP:
+//
+//	GET /products?state=ACTIVE&attr.brand=Acme&gtin_prefix=0001234&page=2
+//	GET /products/{gtin}
+//	GET /products/{gtin}/history
+//
+// state may be repeated to allow several states, and every attr.<key>
+// parameter must match. Products are listed by GTIN, a page at a time.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/sawtooth-sdk-go/logging"
+	"github.com/tross-tyson/mdata_go/src/gs1"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/mdata_indexer/indexer"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+var logger *logging.Logger = logging.Get()
+
+// Query parameter prefix selecting an attribute value
+const attributeParam = "attr."
+
+// ProductPage is a page of the products listed
+type ProductPage struct {
+	Products []*model.Product `json:"products"`
+	Page     int              `json:"page"`
+	// Page listing the following products, 0 on the last page
+	NextPage int `json:"next_page,omitempty"`
+}
+
+// Error is the body of an error response
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Server answers queries from the catalogue
+type Server struct {
+	catalogue *indexer.Catalogue
+	pageSize  int
+}
+
+func NewServer(catalogue *indexer.Catalogue, pageSize int) *Server {
+	return &Server{catalogue: catalogue, pageSize: pageSize}
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %v is not allowed", r.Method))
+		return
+	}
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(path) == 1 && path[0] == "products":
+		server.listProducts(w, r)
+	case len(path) == 2 && path[0] == "products":
+		server.showProduct(w, path[1])
+	case len(path) == 3 && path[0] == "products" && path[2] == "history":
+		server.showHistory(w, path[1])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("No resource at %v", r.URL.Path))
+	}
+}
+
+func (server *Server) listProducts(w http.ResponseWriter, r *http.Request) {
+	filter, page, err := parseQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// One more product than a page tells whether another page follows
+	products, err := server.catalogue.Products(filter, (page-1)*server.pageSize, server.pageSize+1)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	result := &ProductPage{Products: products, Page: page}
+	if len(products) > server.pageSize {
+		result.Products = products[:server.pageSize]
+		result.NextPage = page + 1
+	}
+	writeJson(w, http.StatusOK, result)
+}
+
+func (server *Server) showProduct(w http.ResponseWriter, code string) {
+	gtin, err := gs1.NormalizeStoredGtin(code)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	product, err := server.catalogue.Product(gtin)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	if product == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("Product %v not found", gtin))
+		return
+	}
+	writeJson(w, http.StatusOK, product)
+}
+
+func (server *Server) showHistory(w http.ResponseWriter, code string) {
+	gtin, err := gs1.NormalizeStoredGtin(code)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	entries, err := server.catalogue.History(gtin)
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+	if len(entries) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("No history for product %v", gtin))
+		return
+	}
+	writeJson(w, http.StatusOK, entries)
+}
+
+// parseQuery reads the filter and page of a product listing
+func parseQuery(r *http.Request) (*client.ProductFilter, int, error) {
+	filter := &client.ProductFilter{Attributes: map[string]string{}}
+	page := 1
+	for param, values := range r.URL.Query() {
+		switch {
+		case param == "state":
+			filter.States = values
+		case param == "gtin_prefix":
+			filter.GtinPrefix = values[0]
+			if len(filter.GtinPrefix) > gs1.GtinLength || strings.Trim(filter.GtinPrefix, "0123456789") != "" {
+				return nil, 0, fmt.Errorf("Invalid gtin_prefix '%v': a GTIN-14 prefix is at most %v digits",
+					filter.GtinPrefix, gs1.GtinLength)
+			}
+		case param == "page":
+			var err error
+			page, err = strconv.Atoi(values[0])
+			if err != nil || page < 1 {
+				return nil, 0, fmt.Errorf("Invalid page '%v': pages are numbered from 1", values[0])
+			}
+		case strings.HasPrefix(param, attributeParam) && len(param) > len(attributeParam):
+			if len(values) > 1 {
+				return nil, 0, fmt.Errorf("Attribute %v is given more than one value", param)
+			}
+			filter.Attributes[strings.TrimPrefix(param, attributeParam)] = values[0]
+		default:
+			return nil, 0, fmt.Errorf("Unknown query parameter '%v'", param)
+		}
+	}
+	return filter, page, nil
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		logger.Warnf("Error writing response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, &Error{Error: err.Error()})
+}
+
+// writeInternalError logs err and answers without its details
+func writeInternalError(w http.ResponseWriter, err error) {
+	logger.Errorf("Query failed: %v", err)
+	writeError(w, http.StatusInternalServerError, fmt.Errorf("Query failed"))
+}
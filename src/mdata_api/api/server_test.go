@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/mdata_indexer/indexer"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"github.com/tross-tyson/mdata_go/src/protobuf/history_pb2"
+)
+
+var testProducts = []*model.Product{
+	{Gtin: "00012345600012", State: "ACTIVE", Creator: "02aa", Owners: []string{"02aa"}, Version: 2,
+		Attributes: model.Attributes{"brand": "Acme", "uom": "cases"}},
+	{Gtin: "00012345600029", State: "INACTIVE", Creator: "02aa", Owners: []string{"02aa"}, Version: 1,
+		Attributes: model.Attributes{"brand": "Acme"}},
+	{Gtin: "00076543200016", State: "ACTIVE", Creator: "02bb", Owners: []string{"02bb"}, Version: 1,
+		Attributes: model.Attributes{"brand": "Other"}},
+}
+
+var testHistory = []*history_pb2.HistoryEntry{
+	{Version: 1, Signer: "02aa", Action: "create", Timestamp: 10, State: "ACTIVE", Owners: []string{"02aa"},
+		Changes: []*history_pb2.AttributeChange{{Kind: history_pb2.AttributeChange_ADDED, Key: "brand", NewValue: "Acme"}}},
+	{Version: 2, Signer: "02aa", Action: "update", Timestamp: 20, PreviousState: "ACTIVE", State: "ACTIVE",
+		Changes: []*history_pb2.AttributeChange{{Kind: history_pb2.AttributeChange_ADDED, Key: "uom", NewValue: "cases"}}},
+}
+
+// testServer serves an index holding testProducts and the history of the
+// first of them, with pages of two products
+func testServer(t *testing.T) (*httptest.Server, func()) {
+	dir, err := ioutil.TempDir("", "mdata_api")
+	assert.Nil(t, err)
+	path := filepath.Join(dir, "mdata.db")
+
+	var changes []*client.StateChange
+	for _, product := range testProducts {
+		data, err := model.EncodeProducts([]*model.Product{product})
+		assert.Nil(t, err)
+		changes = append(changes, &client.StateChange{Address: model.ProductAddress(product.Gtin), Value: data})
+	}
+	data, err := proto.Marshal(&history_pb2.HistoryContainer{Entries: []*history_pb2.ProductHistory{
+		{Gtin: testProducts[0].Gtin, Entries: testHistory},
+	}})
+	assert.Nil(t, err)
+	changes = append(changes, &client.StateChange{Address: model.HistoryAddress(testProducts[0].Gtin, 1), Value: data})
+
+	index, err := indexer.Open(path, 10)
+	assert.Nil(t, err)
+	assert.Nil(t, index.ApplyBlock(&client.BlockEvents{BlockId: "genesis", PreviousBlockId: client.NullBlockId,
+		StateChanges: changes}))
+	index.Close()
+
+	catalogue, err := indexer.OpenCatalogue(path)
+	assert.Nil(t, err)
+	server := httptest.NewServer(NewServer(catalogue, 2))
+	return server, func() {
+		server.Close()
+		catalogue.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func get(t *testing.T, url string, body interface{}) int {
+	response, err := http.Get(url)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(body))
+	return response.StatusCode
+}
+
+func TestListProducts(t *testing.T) {
+	server, cleanup := testServer(t)
+	defer cleanup()
+
+	testCases := map[string]struct {
+		query    string
+		expected *ProductPage
+	}{
+		"firstPage": {
+			query:    "",
+			expected: &ProductPage{Products: testProducts[:2], Page: 1, NextPage: 2},
+		},
+		"lastPage": {
+			query:    "?page=2",
+			expected: &ProductPage{Products: testProducts[2:], Page: 2},
+		},
+		"pastLastPage": {
+			query:    "?page=3",
+			expected: &ProductPage{Products: []*model.Product{}, Page: 3},
+		},
+		"state": {
+			query:    "?state=ACTIVE",
+			expected: &ProductPage{Products: []*model.Product{testProducts[0], testProducts[2]}, Page: 1},
+		},
+		"states": {
+			query:    "?state=INACTIVE&state=DISCONTINUED",
+			expected: &ProductPage{Products: testProducts[1:2], Page: 1},
+		},
+		"attributes": {
+			query:    "?attr.brand=Acme&attr.uom=cases",
+			expected: &ProductPage{Products: testProducts[:1], Page: 1},
+		},
+		"gtinPrefix": {
+			query:    "?gtin_prefix=0007654",
+			expected: &ProductPage{Products: testProducts[2:], Page: 1},
+		},
+		"combined": {
+			query:    "?state=ACTIVE&attr.brand=Acme&gtin_prefix=0001234",
+			expected: &ProductPage{Products: testProducts[:1], Page: 1},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Logf("Running test case: %s", name)
+		page := &ProductPage{}
+		assert.Equal(t, http.StatusOK, get(t, server.URL+"/products"+test.query, page))
+		assert.Equal(t, test.expected, page)
+	}
+}
+
+func TestListProductsInvalid(t *testing.T) {
+	server, cleanup := testServer(t)
+	defer cleanup()
+
+	for _, query := range []string{"?page=0", "?page=next", "?gtin_prefix=12ab", "?gtin_prefix=000123456000123",
+		"?attr.brand=Acme&attr.brand=Other", "?brand=Acme"} {
+		t.Logf("Running test case: %s", query)
+		body := &Error{}
+		assert.Equal(t, http.StatusBadRequest, get(t, server.URL+"/products"+query, body))
+		assert.NotEmpty(t, body.Error)
+	}
+}
+
+func TestShowProduct(t *testing.T) {
+	server, cleanup := testServer(t)
+	defer cleanup()
+
+	product := &model.Product{}
+	assert.Equal(t, http.StatusOK, get(t, server.URL+"/products/00012345600012", product))
+	assert.Equal(t, testProducts[0], product)
+
+	// Shorter forms of the GTIN are accepted
+	product = &model.Product{}
+	assert.Equal(t, http.StatusOK, get(t, server.URL+"/products/076543200016", product))
+	assert.Equal(t, testProducts[2], product)
+
+	assert.Equal(t, http.StatusNotFound, get(t, server.URL+"/products/00012345600036", &Error{}))
+	// Family version 1.0 stored GTIN-14s without checking the check digit, so
+	// only the shorter forms must have a valid one
+	assert.Equal(t, http.StatusNotFound, get(t, server.URL+"/products/00012345600013", &Error{}))
+	assert.Equal(t, http.StatusBadRequest, get(t, server.URL+"/products/012345678900", &Error{}))
+	assert.Equal(t, http.StatusNotFound, get(t, server.URL+"/organizations", &Error{}))
+}
+
+func TestShowHistory(t *testing.T) {
+	server, cleanup := testServer(t)
+	defer cleanup()
+
+	var entries []*indexer.HistoryEntry
+	assert.Equal(t, http.StatusOK, get(t, server.URL+"/products/00012345600012/history", &entries))
+	assert.Equal(t, []*indexer.HistoryEntry{
+		{Version: 1, Signer: "02aa", Action: "create", Timestamp: 10, State: "ACTIVE", Owners: []string{"02aa"},
+			Changes: []indexer.AttributeChange{{Kind: "added", Key: "brand", NewValue: "Acme"}}},
+		{Version: 2, Signer: "02aa", Action: "update", Timestamp: 20, PreviousState: "ACTIVE", State: "ACTIVE",
+			Owners: []string{}, Changes: []indexer.AttributeChange{{Kind: "added", Key: "uom", NewValue: "cases"}}},
+	}, entries)
+
+	assert.Equal(t, http.StatusNotFound, get(t, server.URL+"/products/00012345600029/history", &Error{}))
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	server, cleanup := testServer(t)
+	defer cleanup()
+
+	response, err := http.Post(server.URL+"/products", "application/json", nil)
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+	assert.Equal(t, http.MethodGet, response.Header.Get("Allow"))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hyperledger/sawtooth-sdk-go/logging"
+	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/mdata_api/api"
+	"github.com/tross-tyson/mdata_go/src/mdata_indexer/indexer"
+	"net/http"
+	"os"
+)
+
+type Opts struct {
+	Verbose  []bool `short:"v" long:"verbose" description:"Increase verbosity"`
+	Bind     string `short:"b" long:"bind" description:"Address to serve the API on" default:"localhost:8090"`
+	Database string `long:"database" description:"SQLite database written by mdata_indexer" default:"/var/lib/mdata_indexer/mdata.db"`
+	PageSize int    `long:"page-size" description:"Number of products listed per page" default:"100"`
+}
+
+func main() {
+	var opts Opts
+
+	logger := logging.Get()
+
+	parser := flags.NewParser(&opts, flags.Default)
+	remaining, err := parser.Parse()
+	if err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		} else {
+			logger.Errorf("Failed to parse args: %v", err)
+			os.Exit(2)
+		}
+	}
+
+	if len(remaining) > 0 {
+		fmt.Printf("Error: Unrecognized arguments passed: %v\n", remaining)
+		os.Exit(2)
+	}
+
+	switch len(opts.Verbose) {
+	case 2:
+		logger.SetLevel(logging.DEBUG)
+	case 1:
+		logger.SetLevel(logging.INFO)
+	default:
+		logger.SetLevel(logging.WARN)
+	}
+
+	if opts.PageSize < 1 || opts.PageSize > 1000 {
+		fmt.Printf("Error: --page-size must be between 1 and 1000\n")
+		os.Exit(2)
+	}
+
+	catalogue, err := indexer.OpenCatalogue(opts.Database)
+	if err != nil {
+		logger.Errorf("Failed to open %v: %v", opts.Database, err)
+		os.Exit(1)
+	}
+	defer catalogue.Close()
+	logger.Infof("Serving %v on %v", opts.Database, opts.Bind)
+
+	err = http.ListenAndServe(opts.Bind, api.NewServer(catalogue, opts.PageSize))
+	if err != nil {
+		logger.Error("API stopped: ", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,362 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/messaging"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/client_event_pb2"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/events_pb2"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/transaction_receipt_pb2"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/validator_pb2"
+	zmq "github.com/pebbe/zmq4"
+	"github.com/tross-tyson/mdata_go/src/gs1"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"strconv"
+	"strings"
+)
+
+// The validator emits a block commit event for every block added to the
+// chain, carrying the block's id and number, and a state delta event listing
+// the changes the block made to state
+const (
+	blockCommitEvent = "sawtooth/block-commit"
+	stateDeltaEvent  = "sawtooth/state-delta"
+)
+
+// NullBlockId is the previous block id of the genesis block. Subscribing with
+// it as the last known block delivers the events of every block.
+const NullBlockId = "0000000000000000"
+
+// ProductEvent is a product change emitted by the transaction processor
+type ProductEvent struct {
+	BlockId       string            `json:"block_id"`
+	BlockNum      uint64            `json:"block_num"`
+	EventType     string            `json:"event_type"`
+	Gtin          string            `json:"gtin"`
+	Signer        string            `json:"signer"`
+	State         string            `json:"state"`
+	PreviousState string            `json:"previous_state,omitempty"`
+	Attributes    map[string]string `json:"attributes"`
+	// The product after the change, or as it was before a delete
+	Product *model.Product `json:"product,omitempty"`
+}
+
+// BlockEvents are the product events or state changes of one committed
+// block. Blocks without a matching event are delivered too, so a subscriber
+// always knows the last block it has seen.
+type BlockEvents struct {
+	BlockId         string
+	BlockNum        uint64
+	PreviousBlockId string
+	Events          []*ProductEvent
+	// Changes to mdata state, in the order they were made
+	StateChanges []*StateChange
+}
+
+// StateChange is a change a block made to an address of mdata state
+type StateChange struct {
+	Address string
+	// Data stored at the address, nil when Deleted
+	Value   []byte
+	Deleted bool
+}
+
+// EventFilter selects the product events a subscription receives. The zero
+// value receives every product event.
+type EventFilter struct {
+	// Event types to receive, any of model.EventTypes
+	EventTypes []string
+	// GTIN of the only product to receive events for
+	Gtin string
+}
+
+// EventStream receives the events of the blocks committed on the validator
+//
+//	stream, err := client.Subscribe(url, filter, nil)
+//	defer stream.Close()
+//	for {
+//		block, err := stream.Next()
+//	}
+type EventStream struct {
+	context    *zmq.Context
+	connection *messaging.ZmqConnection
+}
+
+// Subscribe connects to the validator at url and subscribes to the product
+// events matching filter. knownBlockIds lists the blocks the subscriber has
+// seen, newest first: the events of the blocks committed after the newest of
+// them still on the chain are delivered first, so a subscriber resuming from
+// the blocks it saw misses no change. Without knownBlockIds the events of
+// the blocks committed from now on are delivered.
+func Subscribe(url string, filter EventFilter, knownBlockIds []string) (*EventStream, error) {
+	request, err := subscribeRequest(filter, knownBlockIds)
+	if err != nil {
+		return nil, err
+	}
+	return subscribe(url, request)
+}
+
+// SubscribeStateDeltas connects to the validator at url and subscribes to the
+// changes made to mdata state. knownBlockIds lists the blocks the subscriber
+// has seen, newest first: the changes of the blocks committed after the
+// newest of them still on the chain are delivered first. With NullBlockId
+// every block is delivered, starting with the genesis block.
+func SubscribeStateDeltas(url string, knownBlockIds []string) (*EventStream, error) {
+	return subscribe(url, &client_event_pb2.ClientEventsSubscribeRequest{
+		Subscriptions: []*events_pb2.EventSubscription{
+			{EventType: blockCommitEvent},
+			{EventType: stateDeltaEvent, Filters: []*events_pb2.EventFilter{{
+				Key:         "address",
+				MatchString: "^" + model.Namespace + ".*",
+				FilterType:  events_pb2.EventFilter_REGEX_ANY,
+			}}},
+		},
+		LastKnownBlockIds: knownBlockIds,
+	})
+}
+
+func subscribe(url string, request *client_event_pb2.ClientEventsSubscribeRequest) (*EventStream, error) {
+	data, err := proto.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to serialize subscription: %v", err)
+	}
+
+	context, err := zmq.NewContext()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create ZMQ context: %v", err)
+	}
+	connection, err := messaging.NewConnection(context, zmq.DEALER, url, false)
+	if err != nil {
+		context.Term()
+		return nil, fmt.Errorf("Unable to connect to validator at %v: %v", url, err)
+	}
+	stream := &EventStream{context: context, connection: connection}
+
+	response := &client_event_pb2.ClientEventsSubscribeResponse{}
+	err = stream.request(validator_pb2.Message_CLIENT_EVENTS_SUBSCRIBE_REQUEST, data, response)
+	if err == nil {
+		switch response.GetStatus() {
+		case client_event_pb2.ClientEventsSubscribeResponse_OK:
+		case client_event_pb2.ClientEventsSubscribeResponse_UNKNOWN_BLOCK:
+			err = fmt.Errorf("None of the blocks %v is known to the validator", strings.Join(request.GetLastKnownBlockIds(), ", "))
+		default:
+			err = fmt.Errorf("Subscription failed: %v %v", response.GetStatus(), response.GetResponseMessage())
+		}
+	}
+	if err != nil {
+		stream.close()
+		return nil, err
+	}
+	return stream, nil
+}
+
+// subscribeRequest builds the subscription to the block commit event and
+// the product events matching filter
+func subscribeRequest(filter EventFilter, knownBlockIds []string) (*client_event_pb2.ClientEventsSubscribeRequest, error) {
+	var filters []*events_pb2.EventFilter
+	if filter.Gtin != "" {
+		gtin, err := gs1.NormalizeStoredGtin(filter.Gtin)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, &events_pb2.EventFilter{
+			Key:         model.EventGtin,
+			MatchString: gtin,
+			FilterType:  events_pb2.EventFilter_SIMPLE_ALL,
+		})
+	}
+
+	eventTypes := filter.EventTypes
+	if len(eventTypes) == 0 {
+		eventTypes = model.EventTypes
+	}
+
+	request := &client_event_pb2.ClientEventsSubscribeRequest{
+		Subscriptions: []*events_pb2.EventSubscription{{EventType: blockCommitEvent}},
+	}
+	for _, eventType := range eventTypes {
+		if !isEventType(eventType) {
+			return nil, fmt.Errorf("Unknown event type '%v', expected one of %v", eventType, strings.Join(model.EventTypes, ", "))
+		}
+		request.Subscriptions = append(request.Subscriptions, &events_pb2.EventSubscription{
+			EventType: eventType,
+			Filters:   filters,
+		})
+	}
+	request.LastKnownBlockIds = knownBlockIds
+	return request, nil
+}
+
+func isEventType(eventType string) bool {
+	for _, known := range model.EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Next waits for the next block to be committed and returns its product
+// events
+func (stream *EventStream) Next() (*BlockEvents, error) {
+	for {
+		_, message, err := stream.connection.RecvMsg()
+		if err != nil {
+			return nil, fmt.Errorf("Error receiving events: %v", err)
+		}
+		switch message.GetMessageType() {
+		case validator_pb2.Message_CLIENT_EVENTS:
+			return decodeBlockEvents(message.GetContent())
+		case validator_pb2.Message_PING_REQUEST:
+			// The validator drops connections that stop answering
+			err = stream.connection.SendMsg(&validator_pb2.Message{
+				MessageType:   validator_pb2.Message_PING_RESPONSE,
+				CorrelationId: message.GetCorrelationId(),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Error answering ping: %v", err)
+			}
+		}
+	}
+}
+
+// Close unsubscribes and disconnects from the validator. The connection is
+// not safe for concurrent use, so Close must not be called while Next waits.
+func (stream *EventStream) Close() error {
+	data, err := proto.Marshal(&client_event_pb2.ClientEventsUnsubscribeRequest{})
+	if err == nil {
+		err = stream.request(validator_pb2.Message_CLIENT_EVENTS_UNSUBSCRIBE_REQUEST, data,
+			&client_event_pb2.ClientEventsUnsubscribeResponse{})
+	}
+	stream.close()
+	return err
+}
+
+func (stream *EventStream) close() {
+	stream.connection.Close()
+	stream.context.Term()
+}
+
+// request sends a message to the validator and decodes its reply into
+// response
+func (stream *EventStream) request(messageType validator_pb2.Message_MessageType, data []byte, response proto.Message) error {
+	corrId, err := stream.connection.SendNewMsg(messageType, data)
+	if err != nil {
+		return fmt.Errorf("Error sending %v: %v", messageType, err)
+	}
+	_, message, err := stream.connection.RecvMsgWithId(corrId)
+	if err != nil {
+		return fmt.Errorf("Error receiving reply to %v: %v", messageType, err)
+	}
+	err = proto.Unmarshal(message.GetContent(), response)
+	if err != nil {
+		return fmt.Errorf("Error decoding reply to %v: %v", messageType, err)
+	}
+	return nil
+}
+
+// decodeBlockEvents reads the events the validator sends for a committed
+// block
+func decodeBlockEvents(data []byte) (*BlockEvents, error) {
+	eventList := &events_pb2.EventList{}
+	err := proto.Unmarshal(data, eventList)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding events: %v", err)
+	}
+
+	block := &BlockEvents{}
+	for _, event := range eventList.GetEvents() {
+		attributes := make(map[string]string)
+		for _, attribute := range event.GetAttributes() {
+			attributes[attribute.GetKey()] = attribute.GetValue()
+		}
+		switch event.GetEventType() {
+		case blockCommitEvent:
+			block.BlockId = attributes["block_id"]
+			block.PreviousBlockId = attributes["previous_block_id"]
+			block.BlockNum, err = strconv.ParseUint(attributes["block_num"], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid block number '%v'", attributes["block_num"])
+			}
+			continue
+		case stateDeltaEvent:
+			block.StateChanges, err = decodeStateChanges(event.GetData())
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		productEvent := &ProductEvent{
+			EventType:     event.GetEventType(),
+			Gtin:          attributes[model.EventGtin],
+			Signer:        attributes[model.EventSigner],
+			State:         attributes[model.EventState],
+			PreviousState: attributes[model.EventPreviousState],
+			Attributes:    make(map[string]string),
+		}
+		for key, value := range attributes {
+			if strings.HasPrefix(key, model.EventAttributePrefix) {
+				productEvent.Attributes[strings.TrimPrefix(key, model.EventAttributePrefix)] = value
+			}
+		}
+		products, err := model.DecodeProducts(event.GetData())
+		if err != nil {
+			return nil, err
+		}
+		if len(products) > 0 {
+			productEvent.Product = products[0]
+		}
+		block.Events = append(block.Events, productEvent)
+	}
+	if block.BlockId == "" {
+		return nil, errors.New("Events received without a block commit event")
+	}
+	for _, event := range block.Events {
+		event.BlockId = block.BlockId
+		event.BlockNum = block.BlockNum
+	}
+	return block, nil
+}
+
+// decodeStateChanges reads the changes to mdata state from the data of a
+// state delta event, which lists the changes to every namespace
+func decodeStateChanges(data []byte) ([]*StateChange, error) {
+	changeList := &transaction_receipt_pb2.StateChangeList{}
+	err := proto.Unmarshal(data, changeList)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding state changes: %v", err)
+	}
+
+	var changes []*StateChange
+	for _, change := range changeList.GetStateChanges() {
+		if !strings.HasPrefix(change.GetAddress(), model.Namespace) {
+			continue
+		}
+		switch change.GetType() {
+		case transaction_receipt_pb2.StateChange_SET:
+			changes = append(changes, &StateChange{Address: change.GetAddress(), Value: change.GetValue()})
+		case transaction_receipt_pb2.StateChange_DELETE:
+			changes = append(changes, &StateChange{Address: change.GetAddress(), Deleted: true})
+		}
+	}
+	return changes, nil
+}
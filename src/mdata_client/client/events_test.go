@@ -0,0 +1,111 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/events_pb2"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/transaction_receipt_pb2"
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+func TestSubscribeRequest(t *testing.T) {
+	request, err := subscribeRequest(EventFilter{EventTypes: []string{model.EventStateChanged}, Gtin: "012345678905"}, []string{"b2", "b1"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b2", "b1"}, request.GetLastKnownBlockIds())
+	assert.Len(t, request.GetSubscriptions(), 2)
+	assert.Equal(t, blockCommitEvent, request.GetSubscriptions()[0].GetEventType())
+	assert.Empty(t, request.GetSubscriptions()[0].GetFilters())
+	subscription := request.GetSubscriptions()[1]
+	assert.Equal(t, model.EventStateChanged, subscription.GetEventType())
+	// The filter matches the GTIN-14 the processor stores
+	assert.Equal(t, "00012345678905", subscription.GetFilters()[0].GetMatchString())
+
+	request, err = subscribeRequest(EventFilter{}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, request.GetSubscriptions(), 1+len(model.EventTypes))
+	assert.Empty(t, request.GetLastKnownBlockIds())
+
+	_, err = subscribeRequest(EventFilter{EventTypes: []string{"mdata/unknown"}}, nil)
+	assert.NotNil(t, err)
+}
+
+func TestDecodeBlockEvents(t *testing.T) {
+	product := &model.Product{Gtin: "00012345600012", State: "INACTIVE", Attributes: model.Attributes{"uom": "cases"}, Version: 2}
+	productData, _ := model.EncodeProducts([]*model.Product{product})
+	blockCommit := &events_pb2.Event{
+		EventType: blockCommitEvent,
+		Attributes: []*events_pb2.Event_Attribute{
+			{Key: "block_id", Value: "b2"},
+			{Key: "block_num", Value: "7"},
+			{Key: "previous_block_id", Value: "b1"},
+		},
+	}
+	stateChanged := &events_pb2.Event{
+		EventType: model.EventStateChanged,
+		Attributes: []*events_pb2.Event_Attribute{
+			{Key: "gtin", Value: "00012345600012"},
+			{Key: "signer", Value: "02aa"},
+			{Key: "state", Value: "INACTIVE"},
+			{Key: "previous_state", Value: "ACTIVE"},
+			{Key: "attr.uom", Value: "cases"},
+		},
+		Data: productData,
+	}
+	// Changes to other namespaces are left out
+	stateChanges, _ := proto.Marshal(&transaction_receipt_pb2.StateChangeList{
+		StateChanges: []*transaction_receipt_pb2.StateChange{
+			{Address: "000000a87cb5eafdcca6a8cde0fb0dec1400c5ab274474a6aa82c12840f169a04216b7", Value: []byte("setting"), Type: transaction_receipt_pb2.StateChange_SET},
+			{Address: model.ProductAddress("00012345600012"), Value: productData, Type: transaction_receipt_pb2.StateChange_SET},
+			{Address: model.HistoryAddress("00012345600029", 1), Type: transaction_receipt_pb2.StateChange_DELETE},
+		},
+	})
+	stateDelta := &events_pb2.Event{
+		EventType:  stateDeltaEvent,
+		Attributes: []*events_pb2.Event_Attribute{{Key: "address", Value: model.ProductAddress("00012345600012")}},
+		Data:       stateChanges,
+	}
+
+	tests := map[string]struct {
+		events []*events_pb2.Event
+		block  *BlockEvents
+		fails  bool
+	}{
+		"productEvent": {
+			events: []*events_pb2.Event{blockCommit, stateChanged},
+			block: &BlockEvents{BlockId: "b2", BlockNum: 7, PreviousBlockId: "b1", Events: []*ProductEvent{{
+				BlockId: "b2", BlockNum: 7, EventType: model.EventStateChanged, Gtin: "00012345600012", Signer: "02aa",
+				State: "INACTIVE", PreviousState: "ACTIVE", Attributes: map[string]string{"uom": "cases"}, Product: product,
+			}}},
+		},
+		"blockOnly": {
+			events: []*events_pb2.Event{blockCommit},
+			block:  &BlockEvents{BlockId: "b2", BlockNum: 7, PreviousBlockId: "b1"},
+		},
+		"stateDelta": {
+			events: []*events_pb2.Event{blockCommit, stateDelta},
+			block: &BlockEvents{BlockId: "b2", BlockNum: 7, PreviousBlockId: "b1", StateChanges: []*StateChange{
+				{Address: model.ProductAddress("00012345600012"), Value: productData},
+				{Address: model.HistoryAddress("00012345600029", 1), Deleted: true},
+			}},
+		},
+		"noBlockCommit": {
+			events: []*events_pb2.Event{stateChanged},
+			fails:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		data, err := proto.Marshal(&events_pb2.EventList{Events: test.events})
+		assert.Nil(t, err)
+		block, err := decodeBlockEvents(data)
+		if test.fails {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, test.block, block)
+	}
+}
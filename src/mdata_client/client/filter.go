@@ -0,0 +1,78 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package client
+
+import (
+	"github.com/tross-tyson/mdata_go/src/model"
+	"strings"
+)
+
+// ProductFilter selects products. A product matches when it passes every
+// criterion set; the zero value matches every product.
+type ProductFilter struct {
+	// States the product may be in, any of them
+	States []string
+	// Attribute values the product must have, all of them
+	Attributes map[string]string
+	// Attribute keys the product must have, whatever their value
+	AttributesExist []string
+	// Start of the product's GTIN-14
+	GtinPrefix string
+}
+
+// Match reports whether product passes the filter
+func (filter *ProductFilter) Match(product *model.Product) bool {
+	if !strings.HasPrefix(product.Gtin, filter.GtinPrefix) {
+		return false
+	}
+	if len(filter.States) > 0 {
+		found := false
+		for _, state := range filter.States {
+			if product.State == state {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for key, value := range filter.Attributes {
+		actual, ok := product.Attributes[key]
+		if !ok || actual != value {
+			return false
+		}
+	}
+	for _, key := range filter.AttributesExist {
+		if _, ok := product.Attributes[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the products passing the filter
+func (filter *ProductFilter) Filter(products []*model.Product) []*model.Product {
+	var matches []*model.Product
+	for _, product := range products {
+		if filter.Match(product) {
+			matches = append(matches, product)
+		}
+	}
+	return matches
+}
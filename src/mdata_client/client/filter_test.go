@@ -0,0 +1,58 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+func TestProductFilter(t *testing.T) {
+	acme := &model.Product{Gtin: "00012345600012", State: "ACTIVE", Attributes: map[string]string{"brand": "Acme", "uom": "cases"}}
+	other := &model.Product{Gtin: "00098765400017", State: "DISCONTINUED", Attributes: map[string]string{"brand": "Other"}}
+	bare := &model.Product{Gtin: "00012345600029", State: "INACTIVE", Attributes: map[string]string{}}
+	products := []*model.Product{acme, other, bare}
+
+	tests := map[string]struct {
+		filter  ProductFilter
+		matches []*model.Product
+	}{
+		"empty": {
+			filter:  ProductFilter{},
+			matches: products,
+		},
+		"state": {
+			filter:  ProductFilter{States: []string{"DISCONTINUED"}},
+			matches: []*model.Product{other},
+		},
+		"anyState": {
+			filter:  ProductFilter{States: []string{"ACTIVE", "INACTIVE"}},
+			matches: []*model.Product{acme, bare},
+		},
+		"attribute": {
+			filter:  ProductFilter{Attributes: map[string]string{"brand": "Acme"}},
+			matches: []*model.Product{acme},
+		},
+		"emptyAttributeValue": {
+			filter:  ProductFilter{Attributes: map[string]string{"uom": ""}},
+			matches: nil,
+		},
+		"attributeExists": {
+			filter:  ProductFilter{AttributesExist: []string{"brand"}},
+			matches: []*model.Product{acme, other},
+		},
+		"gtinPrefix": {
+			filter:  ProductFilter{GtinPrefix: "000123456"},
+			matches: []*model.Product{acme, bare},
+		},
+		"combined": {
+			filter:  ProductFilter{GtinPrefix: "000123456", AttributesExist: []string{"uom"}, States: []string{"ACTIVE"}},
+			matches: []*model.Product{acme},
+		},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		assert.Equal(t, test.matches, test.filter.Filter(products))
+	}
+}
@@ -0,0 +1,404 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package client
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/transaction_pb2"
+	"github.com/tross-tyson/mdata_go/src/gs1"
+	"github.com/tross-tyson/mdata_go/src/lifecycle"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/constants"
+	"gopkg.in/yaml.v2"
+	"io"
+	"strings"
+)
+
+// Status of an ImportResult. Rows are COMMITTED or INVALID once the validator
+// decided on their batch, and PENDING when that took longer than the wait.
+const (
+	ImportCommitted    = "COMMITTED"
+	ImportInvalid      = "INVALID"
+	ImportPending      = "PENDING"
+	ImportUnknown      = "UNKNOWN"
+	ImportNotSubmitted = "NOT_SUBMITTED"
+)
+
+// ImportRow is a product read from a line of an import file
+type ImportRow struct {
+	Line       int
+	Gtin       string
+	State      string
+	Attributes map[string]string
+}
+
+// ImportResult reports what became of the transactions of an ImportRow
+type ImportResult struct {
+	Row     ImportRow
+	Status  string
+	Message string
+}
+
+// ReadImportFile reads products from CSV. The header row names the columns: a
+// gtin column is required, a state column is optional and every other column
+// is an attribute. Empty attribute cells are left out of the product.
+func ReadImportFile(reader io.Reader) ([]ImportRow, error) {
+	lines := &lineReader{reader: bufio.NewReader(reader)}
+	csvReader := csv.NewReader(lines)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("Import file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading import file: %v", err)
+	}
+
+	gtinColumn, stateColumn := -1, -1
+	seen := make(map[string]bool)
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		header[i] = name
+		if name == "" {
+			return nil, fmt.Errorf("Column %v of the import file has no name", i+1)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("Column %v appears twice in the import file", name)
+		}
+		seen[strings.ToLower(name)] = true
+		switch strings.ToLower(name) {
+		case "gtin":
+			gtinColumn = i
+		case "state":
+			stateColumn = i
+		}
+	}
+	if gtinColumn < 0 {
+		return nil, fmt.Errorf("Import file has no gtin column")
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error reading import file: %v", err)
+		}
+		// The record ends on the last line read; quoted values spanning lines
+		// moved its start up
+		line := lines.count
+		for _, value := range record {
+			line -= strings.Count(value, "\n")
+		}
+
+		row := ImportRow{Line: line, Attributes: make(map[string]string)}
+		for i, value := range record {
+			switch i {
+			case gtinColumn:
+				row.Gtin = strings.TrimSpace(value)
+			case stateColumn:
+				row.State = strings.TrimSpace(value)
+			default:
+				if value != "" {
+					row.Attributes[header[i]] = value
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// lineReader hands its reader's content out one line at a time, counting the
+// lines, so the count is the line a csv.Reader reading from it has reached
+type lineReader struct {
+	reader *bufio.Reader
+	line   []byte
+	count  int
+}
+
+func (self *lineReader) Read(p []byte) (int, error) {
+	if len(self.line) == 0 {
+		line, err := self.reader.ReadBytes('\n')
+		if len(line) == 0 {
+			return 0, err
+		}
+		self.line = line
+		self.count++
+	}
+	n := copy(p, self.line)
+	self.line = self.line[n:]
+	return n, nil
+}
+
+// Import creates a product for each row, setting its state when the row names
+// one other than the initial state. Rows are sent batchSize transactions to a
+// batch. A batch is committed or rejected as a whole, so the rows of a
+// rejected batch that were not at fault are sent again in a new batch. wait is
+// the time, in seconds, to wait for the validator to decide on the batches.
+//
+// The error is only set when the import could not run; rows the validator
+// rejected are reported in their ImportResult.
+func (mdataClient MdataClient) Import(rows []ImportRow, batchSize uint, wait uint) ([]ImportResult, error) {
+	if batchSize < 1 || batchSize > constants.MAX_BATCH_TRANSACTIONS {
+		return nil, fmt.Errorf("Batch size must be between 1 and %v", constants.MAX_BATCH_TRANSACTIONS)
+	}
+
+	productLifecycle, err := mdataClient.GetLifecycle()
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows the processor would refuse are rejected before they are signed
+	results := make([]ImportResult, len(rows))
+	var pending []int
+	for i, row := range rows {
+		results[i].Row = row
+		err := checkImportRow(row, productLifecycle)
+		if err != nil {
+			results[i].Status = ImportInvalid
+			results[i].Message = err.Error()
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for len(pending) > 0 {
+		pending, err = mdataClient.importRows(rows, pending, results, productLifecycle, batchSize, wait)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func checkImportRow(row ImportRow, productLifecycle *lifecycle.Lifecycle) error {
+	err := gs1.ValidateGtin(row.Gtin)
+	if err != nil {
+		return err
+	}
+	if row.State == "" || row.State == productLifecycle.InitialState {
+		return nil
+	}
+	err = productLifecycle.ValidateState(row.State)
+	if err != nil {
+		return err
+	}
+	return productLifecycle.CheckTransition(productLifecycle.InitialState, row.State, false)
+}
+
+// importBatch is a batch of rows sent by importRows
+type importBatch struct {
+	id   string
+	rows []int
+	// Row each transaction of the batch belongs to, by transaction id
+	transactions map[string]int
+}
+
+// importRows sends the pending rows and records the outcome of their batches
+// in results. It returns the rows to send again.
+func (mdataClient MdataClient) importRows(
+	rows []ImportRow,
+	pending []int,
+	results []ImportResult,
+	productLifecycle *lifecycle.Lifecycle,
+	batchSize uint,
+	wait uint) ([]int, error) {
+
+	var batches []*importBatch
+	var transactions []*transaction_pb2.Transaction
+	batch := &importBatch{transactions: make(map[string]int)}
+
+	send := func() error {
+		if len(transactions) == 0 {
+			return nil
+		}
+		rawBatchList, err := mdataClient.createBatchList(transactions)
+		if err != nil {
+			return fmt.Errorf("Unable to construct batch list: %v", err)
+		}
+		batchList, err := proto.Marshal(&rawBatchList)
+		if err != nil {
+			return fmt.Errorf("Unable to serialize batch list: %v", err)
+		}
+		batch.id = rawBatchList.Batches[0].HeaderSignature
+		_, err = mdataClient.sendRequest(
+			constants.BATCH_SUBMIT_API, batchList, constants.CONTENT_TYPE_OCTET_STREAM, "")
+		if err != nil {
+			// The other batches may still go through
+			for _, row := range batch.rows {
+				results[row].Status = ImportNotSubmitted
+				results[row].Message = err.Error()
+			}
+		} else {
+			batches = append(batches, batch)
+		}
+		transactions = nil
+		batch = &importBatch{transactions: make(map[string]int)}
+		return nil
+	}
+
+	for _, row := range pending {
+		rowTransactions, err := mdataClient.importTransactions(rows[row], productLifecycle)
+		if err != nil {
+			return nil, err
+		}
+		// The transactions of a row always share a batch
+		if uint(len(transactions)+len(rowTransactions)) > batchSize {
+			err = send()
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, transaction := range rowTransactions {
+			batch.transactions[transaction.HeaderSignature] = row
+		}
+		batch.rows = append(batch.rows, row)
+		transactions = append(transactions, rowTransactions...)
+	}
+	err := send()
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, nil
+	}
+
+	var ids []string
+	for _, batch := range batches {
+		ids = append(ids, batch.id)
+	}
+	statuses, err := mdataClient.getBatchStatuses(ids, wait)
+	if err != nil {
+		return nil, err
+	}
+
+	var retry []int
+	for _, batch := range batches {
+		status, ok := statuses[batch.id]
+		if !ok {
+			status = batchStatus{Status: ImportUnknown}
+		}
+		switch status.Status {
+		case ImportCommitted:
+			for _, row := range batch.rows {
+				results[row].Status = ImportCommitted
+			}
+		case ImportInvalid:
+			faulty := make(map[int]bool)
+			for _, transaction := range status.InvalidTransactions {
+				row, ok := batch.transactions[transaction.Id]
+				if ok {
+					faulty[row] = true
+					results[row].Status = ImportInvalid
+					results[row].Message = transaction.Message
+				}
+			}
+			for _, row := range batch.rows {
+				if faulty[row] {
+					continue
+				}
+				if len(faulty) > 0 {
+					retry = append(retry, row)
+				} else {
+					results[row].Status = ImportInvalid
+					results[row].Message = "Batch rejected without naming an invalid transaction"
+				}
+			}
+		default:
+			for _, row := range batch.rows {
+				results[row].Status = status.Status
+				results[row].Message = fmt.Sprintf("Batch %v not committed after waiting %v seconds", batch.id, wait)
+			}
+		}
+	}
+	return retry, nil
+}
+
+// importTransactions builds the transactions creating the product of row
+func (mdataClient MdataClient) importTransactions(row ImportRow, productLifecycle *lifecycle.Lifecycle) ([]*transaction_pb2.Transaction, error) {
+	create := MdataClientAction{}
+	create.action = constants.VERB_CREATE
+	create.gtin = row.Gtin
+	create.attrs = row.Attributes
+	transaction, err := mdataClient.newTransaction(create)
+	if err != nil {
+		return nil, err
+	}
+	transactions := []*transaction_pb2.Transaction{transaction}
+
+	if row.State != "" && row.State != productLifecycle.InitialState {
+		set := MdataClientAction{}
+		set.action = constants.VERB_SET_STATE
+		set.gtin = row.Gtin
+		set.state = row.State
+		// The validator may schedule the transactions of a batch in any
+		// order unless they declare a dependency
+		set.dependencies = []string{transaction.HeaderSignature}
+		transaction, err = mdataClient.newTransaction(set)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
+
+// batchStatus is an entry of the batch_statuses REST API response
+type batchStatus struct {
+	Id                  string `yaml:"id"`
+	Status              string `yaml:"status"`
+	InvalidTransactions []struct {
+		Id      string `yaml:"id"`
+		Message string `yaml:"message"`
+	} `yaml:"invalid_transactions"`
+}
+
+// getBatchStatuses returns the status of each batch by id, waiting up to wait
+// seconds for the batches to be committed. The ids are posted rather than
+// passed in the URL, which would grow too long for large imports.
+func (mdataClient MdataClient) getBatchStatuses(ids []string, wait uint) (map[string]batchStatus, error) {
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to serialize batch ids: %v", err)
+	}
+	apiSuffix := fmt.Sprintf("%s?wait=%d", constants.BATCH_STATUS_API, wait)
+	response, err := mdataClient.sendRequest(apiSuffix, data, constants.CONTENT_TYPE_JSON, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var responseMap struct {
+		Data []batchStatus `yaml:"data"`
+	}
+	err = yaml.Unmarshal([]byte(response), &responseMap)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading response: %v", err)
+	}
+	statuses := make(map[string]batchStatus)
+	for _, status := range responseMap.Data {
+		statuses[status.Id] = status
+	}
+	return statuses, nil
+}
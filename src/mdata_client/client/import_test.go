@@ -0,0 +1,92 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/transaction_pb2"
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/lifecycle"
+)
+
+func TestReadImportFile(t *testing.T) {
+
+	tests := map[string]struct {
+		in   string
+		rows []ImportRow
+		err  bool
+	}{
+		"attributesAndState": {
+			in: "gtin,state,uom,brand\n00012345600012,INACTIVE,cases,Acme\n012345678905,,,\"Acme, Inc.\"\n",
+			rows: []ImportRow{
+				{Line: 2, Gtin: "00012345600012", State: "INACTIVE", Attributes: map[string]string{"uom": "cases", "brand": "Acme"}},
+				{Line: 3, Gtin: "012345678905", Attributes: map[string]string{"brand": "Acme, Inc."}},
+			},
+		},
+		"headerCase": {
+			in:   "GTIN, uom\n00012345600012, lbs\n",
+			rows: []ImportRow{{Line: 2, Gtin: "00012345600012", Attributes: map[string]string{"uom": "lbs"}}},
+		},
+		"multilineValue": {
+			in: "gtin,desc\n00012345600012,\"two\nlines\"\n012345678905,one\n",
+			rows: []ImportRow{
+				{Line: 2, Gtin: "00012345600012", Attributes: map[string]string{"desc": "two\nlines"}},
+				{Line: 4, Gtin: "012345678905", Attributes: map[string]string{"desc": "one"}},
+			},
+		},
+		"blankLinesAndCrlf": {
+			in: "gtin,desc\r\n\r\n00012345600012,\"two\r\nlines\"\r\n\r\n012345678905,one",
+			rows: []ImportRow{
+				{Line: 3, Gtin: "00012345600012", Attributes: map[string]string{"desc": "two\nlines"}},
+				{Line: 6, Gtin: "012345678905", Attributes: map[string]string{"desc": "one"}},
+			},
+		},
+		"noGtinColumn": {
+			in:  "uom\ncases\n",
+			err: true,
+		},
+		"duplicateColumn": {
+			in:  "gtin,uom,UOM\n00012345600012,cases,lbs\n",
+			err: true,
+		},
+		"wrongFieldCount": {
+			in:  "gtin,uom\n00012345600012\n",
+			err: true,
+		},
+		"empty": {
+			in:  "",
+			err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		rows, err := ReadImportFile(strings.NewReader(test.in))
+		if test.err {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, test.rows, rows)
+	}
+}
+
+func TestImportTransactions(t *testing.T) {
+	mdataClient, err := NewMdataClient("", "")
+	assert.Nil(t, err)
+	transactions, err := mdataClient.importTransactions(
+		ImportRow{Line: 2, Gtin: "00012345600012", State: "INACTIVE", Attributes: map[string]string{"uom": "cases"}}, lifecycle.Default)
+	assert.Nil(t, err)
+	assert.Len(t, transactions, 2)
+
+	// The state is set once the product is created
+	header := &transaction_pb2.TransactionHeader{}
+	assert.Nil(t, proto.Unmarshal(transactions[1].Header, header))
+	assert.Equal(t, []string{transactions[0].HeaderSignature}, header.Dependencies)
+
+	transactions, err = mdataClient.importTransactions(
+		ImportRow{Line: 3, Gtin: "00012345600029", Attributes: map[string]string{"uom": "cases"}}, lifecycle.Default)
+	assert.Nil(t, err)
+	assert.Len(t, transactions, 1)
+}
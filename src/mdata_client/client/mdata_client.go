@@ -27,16 +27,23 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/sawtooth-sdk-go/logging"
 	"github.com/hyperledger/sawtooth-sdk-go/protobuf/batch_pb2"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/setting_pb2"
 	"github.com/hyperledger/sawtooth-sdk-go/protobuf/transaction_pb2"
 	"github.com/hyperledger/sawtooth-sdk-go/signing"
+	"github.com/tross-tyson/mdata_go/src/gs1"
+	"github.com/tross-tyson/mdata_go/src/lifecycle"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/commands"  //mdata_client/commands
 	"github.com/tross-tyson/mdata_go/src/mdata_client/constants" //mdata_client/constants
+	"github.com/tross-tyson/mdata_go/src/model"
+	"github.com/tross-tyson/mdata_go/src/protobuf/history_pb2"
+	"github.com/tross-tyson/mdata_go/src/protobuf/mdata_payload_pb2"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"os/user"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -79,25 +86,88 @@ type MdataClient struct {
 }
 
 type MdataClientAction struct {
-	action string
-	gtin   string
-	wait   uint
-	attrs  map[string]string
-	state  string
+	action     string
+	gtin       string
+	wait       uint
+	attrs      map[string]string
+	state      string
+	removeKeys []string
+	newOwner   string
+	org        *Organization
+	agent      *Agent
+	// Version the product must be at for an update, checked when
+	// checkVersion is set
+	expectedVersion uint64
+	checkVersion    bool
+	// Header signatures of the transactions to apply before this one
+	dependencies []string
 }
 
-func (c *MdataClientAction) serializePayload() string {
-	//Convert map[string]string to []string{key=value key=value...} because that is what is expected by the processor payload
-	attributes := []string{}
-	for k, v := range c.attrs {
-		attributes = append(attributes, fmt.Sprintf("%v=%v", k, v))
+// Agent is the agent record sent by the agent_create and agent_update actions
+type Agent struct {
+	PublicKey string
+	OrgId     string
+	Roles     []string
+}
+
+// Organization is the org record sent by the org_create and org_update actions
+type Organization struct {
+	OrgId           string
+	Name            string
+	Admins          []string
+	CompanyPrefixes []string
+}
+
+var payloadActions = map[string]mdata_payload_pb2.MdPayload_Action{
+	constants.VERB_CREATE:       mdata_payload_pb2.MdPayload_CREATE,
+	constants.VERB_UPDATE:       mdata_payload_pb2.MdPayload_UPDATE,
+	constants.VERB_DELETE:       mdata_payload_pb2.MdPayload_DELETE,
+	constants.VERB_SET_STATE:    mdata_payload_pb2.MdPayload_SET,
+	constants.VERB_PATCH:        mdata_payload_pb2.MdPayload_PATCH,
+	constants.VERB_UNSET:        mdata_payload_pb2.MdPayload_UNSET_ATTRIBUTES,
+	constants.VERB_TRANSFER:     mdata_payload_pb2.MdPayload_TRANSFER,
+	constants.VERB_ORG_CREATE:   mdata_payload_pb2.MdPayload_ORG_CREATE,
+	constants.VERB_ORG_UPDATE:   mdata_payload_pb2.MdPayload_ORG_UPDATE,
+	constants.VERB_AGENT_CREATE: mdata_payload_pb2.MdPayload_AGENT_CREATE,
+	constants.VERB_AGENT_UPDATE: mdata_payload_pb2.MdPayload_AGENT_UPDATE,
+}
+
+func (c *MdataClientAction) serializePayload() ([]byte, error) {
+	//Sort the attribute keys so the same action always encodes to the same bytes
+	keys := []string{}
+	for k := range c.attrs {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	return fmt.Sprintf("%v,%v,%v,%v",
-		c.action,
-		c.gtin,
-		strings.Join(attributes, ","),
-		c.state)
+	attributes := []*mdata_payload_pb2.MdPayload_Attribute{}
+	for _, k := range keys {
+		attributes = append(attributes, &mdata_payload_pb2.MdPayload_Attribute{Key: k, Value: c.attrs[k]})
+	}
+
+	payload := &mdata_payload_pb2.MdPayload{
+		Action:          payloadActions[c.action],
+		Gtin:            c.gtin,
+		Attributes:      attributes,
+		State:           c.state,
+		Timestamp:       time.Now().Unix(),
+		RemoveKeys:      c.removeKeys,
+		NewOwner:        c.newOwner,
+		ExpectedVersion: c.expectedVersion,
+		CheckVersion:    c.checkVersion,
+	}
+	if c.org != nil {
+		payload.OrgId = c.org.OrgId
+		payload.OrgName = c.org.Name
+		payload.Admins = c.org.Admins
+		payload.CompanyPrefixes = c.org.CompanyPrefixes
+	}
+	if c.agent != nil {
+		payload.OrgId = c.agent.OrgId
+		payload.PublicKey = c.agent.PublicKey
+		payload.Roles = c.agent.Roles
+	}
+	return proto.Marshal(payload)
 }
 
 func NewMdataClient(url string, keyfile string) (MdataClient, error) {
@@ -137,14 +207,50 @@ func (mdataClient MdataClient) Create(
 }
 
 func (mdataClient MdataClient) Update(
-	// Requires gtin and attributes
-	gtin string, attrs map[string]string, wait uint) (string, error) {
+	// Requires gtin and attributes. With ifVersion the current version of the
+	// product is read first, and the update is rejected if another change is
+	// applied before it.
+	gtin string, attrs map[string]string, ifVersion bool, wait uint) (string, error) {
 	c := MdataClientAction{}
 	c.action = constants.VERB_UPDATE
 	c.gtin = gtin
 	c.wait = wait
 	c.attrs = attrs
 	c.state = ""
+	if ifVersion {
+		version, err := mdataClient.Version(gtin)
+		if err != nil {
+			return "", err
+		}
+		c.expectedVersion = version
+		c.checkVersion = true
+	}
+	return mdataClient.sendTransaction(c, wait)
+}
+
+func (mdataClient MdataClient) Patch(
+	// Requires gtin and attributes to set and/or keys to remove, other attributes are kept
+	gtin string, attrs map[string]string, removeKeys []string, wait uint) (string, error) {
+	c := MdataClientAction{}
+	c.action = constants.VERB_PATCH
+	c.gtin = gtin
+	c.wait = wait
+	c.attrs = attrs
+	c.state = ""
+	c.removeKeys = removeKeys
+	return mdataClient.sendTransaction(c, wait)
+}
+
+func (mdataClient MdataClient) Unset(
+	// Requires gtin and the attribute keys to remove
+	gtin string, keys []string, wait uint) (string, error) {
+	c := MdataClientAction{}
+	c.action = constants.VERB_UNSET
+	c.gtin = gtin
+	c.wait = wait
+	c.attrs = make(map[string]string)
+	c.state = ""
+	c.removeKeys = keys
 	return mdataClient.sendTransaction(c, wait)
 }
 
@@ -172,74 +278,210 @@ func (mdataClient MdataClient) Set(
 	return mdataClient.sendTransaction(c, wait)
 }
 
-func (mdataClient MdataClient) List() ([]string, error) {
+func (mdataClient MdataClient) Transfer(
+	// Requires gtin and the public key of the new owner
+	gtin string, newOwner string, wait uint) (string, error) {
+	c := MdataClientAction{}
+	c.action = constants.VERB_TRANSFER
+	c.gtin = gtin
+	c.wait = wait
+	c.attrs = make(map[string]string)
+	c.state = ""
+	c.newOwner = newOwner
+	return mdataClient.sendTransaction(c, wait)
+}
 
-	// API to call
-	apiSuffix := fmt.Sprintf("%s?address=%s",
-		constants.STATE_API, mdataClient.getPrefix())
-	response, err := mdataClient.sendRequest(apiSuffix, []byte{}, "", "")
+func (mdataClient MdataClient) CreateOrg(
+	// Requires org id, admins default to the signer
+	org Organization, wait uint) (string, error) {
+	c := MdataClientAction{}
+	c.action = constants.VERB_ORG_CREATE
+	c.wait = wait
+	c.attrs = make(map[string]string)
+	c.org = &org
+	return mdataClient.sendTransaction(c, wait)
+}
+
+func (mdataClient MdataClient) UpdateOrg(
+	// Requires org id and admins, replaces the name, admins and company prefixes
+	org Organization, wait uint) (string, error) {
+	c := MdataClientAction{}
+	c.action = constants.VERB_ORG_UPDATE
+	c.wait = wait
+	c.attrs = make(map[string]string)
+	c.org = &org
+	return mdataClient.sendTransaction(c, wait)
+}
+
+func (mdataClient MdataClient) CreateAgent(
+	// Requires public key and org id, roles are optional
+	agent Agent, wait uint) (string, error) {
+	c := MdataClientAction{}
+	c.action = constants.VERB_AGENT_CREATE
+	c.wait = wait
+	c.attrs = make(map[string]string)
+	c.agent = &agent
+	return mdataClient.sendTransaction(c, wait)
+}
+
+func (mdataClient MdataClient) UpdateAgent(
+	// Requires public key, replaces the agent's roles
+	agent Agent, wait uint) (string, error) {
+	c := MdataClientAction{}
+	c.action = constants.VERB_AGENT_UPDATE
+	c.wait = wait
+	c.attrs = make(map[string]string)
+	c.agent = &agent
+	return mdataClient.sendTransaction(c, wait)
+}
+
+// Show returns the product stored under gtin. A GTIN-14 is looked up whatever
+// its check digit, as family version 1.0 stored products without checking it.
+func (mdataClient MdataClient) Show(gtin string) (*model.Product, error) {
+	gtin, err := gs1.NormalizeStoredGtin(gtin)
 	if err != nil {
-		return []string{}, err
+		return nil, err
 	}
 
-	var toReturn []string
+	responseData, err := mdataClient.getStateData(model.ProductAddress(gtin), gtin)
+	if err != nil {
+		return nil, err
+	}
+	products, err := model.DecodeProducts(responseData)
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range products {
+		if product.Gtin == gtin {
+			return product, nil
+		}
+	}
+	return nil, &notFoundError{gtin}
+}
 
-	responseMap := make(map[interface{}]interface{})
-	err = yaml.Unmarshal([]byte(response), &responseMap)
+// Version returns the number of changes made to the product, 0 for products
+// stored before versions were recorded
+func (mdataClient MdataClient) Version(gtin string) (uint64, error) {
+	gtin, err := gs1.NormalizeStoredGtin(gtin)
 	if err != nil {
-		return []string{},
-			fmt.Errorf("Error reading response: %v", err)
+		return 0, err
 	}
-	encodedEntries := responseMap["data"].([]interface{})
 
-	for _, entry := range encodedEntries {
-		entryData, ok := entry.(map[interface{}]interface{})
-		if !ok {
-			return []string{},
-				errors.New("Error reading entry data")
-		}
+	product, err := mdataClient.Show(gtin)
+	if err != nil {
+		return 0, err
+	}
+	return product.Version, nil
+}
 
-		stringData, ok := entryData["data"].(string)
-		if !ok {
-			return []string{},
-				errors.New("Error reading string data")
-		}
+// History returns the changes recorded for gtin, oldest first. The history
+// is stored in pages sharing an address prefix, read in address order, which
+// is version order.
+func (mdataClient MdataClient) History(gtin string) ([]*history_pb2.HistoryEntry, error) {
+	gtin, err := gs1.NormalizeStoredGtin(gtin)
+	if err != nil {
+		return nil, err
+	}
 
-		decodedBytes, err := base64.StdEncoding.DecodeString(stringData)
+	var entries []*history_pb2.HistoryEntry
+	head, start := "", ""
+	for {
+		page, err := mdataClient.getStatePage(model.HistoryPrefix(gtin), head, start, constants.STATE_PAGE_LIMIT)
 		if err != nil {
-			return []string{},
-				fmt.Errorf("Error decoding: %v", err)
+			return nil, err
 		}
-
-		toReturn = append(toReturn, string(decodedBytes))
+		for _, entry := range page.Data {
+			data, err := base64.StdEncoding.DecodeString(entry.Data)
+			if err != nil {
+				return nil, fmt.Errorf("Error decoding: %v", err)
+			}
+			container := &history_pb2.HistoryContainer{}
+			err = proto.Unmarshal(data, container)
+			if err != nil {
+				return nil, fmt.Errorf("Error decoding history: %v", err)
+			}
+			for _, history := range container.GetEntries() {
+				if history.GetGtin() == gtin {
+					entries = append(entries, history.GetEntries()...)
+				}
+			}
+		}
+		if page.Paging.Next == "" {
+			break
+		}
+		head, start = page.Head, page.Paging.NextPosition
+	}
+	if len(entries) == 0 {
+		return nil, &notFoundError{gtin}
 	}
-	return toReturn, nil
+	return entries, nil
 }
 
-func (mdataClient MdataClient) Show(gtin string) (string, error) {
-
-	apiSuffix := fmt.Sprintf("%s/%s", constants.STATE_API, mdataClient.getAddress(gtin))
+// getStateData returns the data stored at address. A missing address is
+// reported as a notFoundError naming gtin.
+func (mdataClient MdataClient) getStateData(address string, gtin string) ([]byte, error) {
+	apiSuffix := fmt.Sprintf("%s/%s", constants.STATE_API, address)
 	response, err := mdataClient.sendRequest(apiSuffix, []byte{}, "", gtin)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	responseMap := make(map[interface{}]interface{})
 	err = yaml.Unmarshal([]byte(response), &responseMap)
 	if err != nil {
-		return "", fmt.Errorf("Error reading response: %v", err)
+		return nil, fmt.Errorf("Error reading response: %v", err)
 	}
 	data, ok := responseMap["data"].(string)
 	if !ok {
-		return "", errors.New("Error reading as string")
+		return nil, errors.New("Error reading as string")
 	}
 	responseData, err := base64.StdEncoding.DecodeString(data)
 	if err != nil {
-		return "", fmt.Errorf("Error decoding response: %v", err)
+		return nil, fmt.Errorf("Error decoding response: %v", err)
 	}
+	return responseData, nil
+}
 
-	strData := string(responseData)
+// GetLifecycle reads the product lifecycle from the mdata.product.states
+// setting, returning lifecycle.Default when the setting is not set
+func (mdataClient MdataClient) GetLifecycle() (*lifecycle.Lifecycle, error) {
+	apiSuffix := fmt.Sprintf("%s/%s", constants.STATE_API, lifecycle.SettingAddress(lifecycle.SettingKey))
+	response, err := mdataClient.sendRequest(apiSuffix, []byte{}, "", "")
+	if _, ok := err.(*notFoundError); ok {
+		return lifecycle.Default, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	responseMap := make(map[interface{}]interface{})
+	err = yaml.Unmarshal([]byte(response), &responseMap)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading response: %v", err)
+	}
+	data, ok := responseMap["data"].(string)
+	if !ok {
+		return nil, errors.New("Error reading as string")
+	}
+	responseData, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding response: %v", err)
+	}
 
-	return fmt.Sprintf("%v", strData), nil
+	setting := &setting_pb2.Setting{}
+	err = proto.Unmarshal(responseData, setting)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding setting: %v", err)
+	}
+	for _, entry := range setting.GetEntries() {
+		if entry.GetKey() == lifecycle.SettingKey {
+			productLifecycle, err := lifecycle.Parse(entry.GetValue())
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %v setting: %v", lifecycle.SettingKey, err)
+			}
+			return productLifecycle, nil
+		}
+	}
+	return lifecycle.Default, nil
 }
 
 func (mdataClient MdataClient) getStatus(
@@ -290,7 +532,7 @@ func (mdataClient MdataClient) sendRequest(
 	}
 	if response.StatusCode == 404 {
 		logger.Debug(fmt.Sprintf("%v", response))
-		return "", fmt.Errorf("No such product: %s", gtin)
+		return "", &notFoundError{gtin}
 	} else if response.StatusCode >= 400 {
 		return "", fmt.Errorf("Error %d: %s", response.StatusCode, response.Status)
 	}
@@ -302,43 +544,25 @@ func (mdataClient MdataClient) sendRequest(
 	return string(reponseBody), nil
 }
 
-func (mdataClient MdataClient) sendTransaction(c MdataClientAction, wait uint) (string, error) {
-	payload := c.serializePayload()
-	gtin := c.gtin
-	// construct the address
-	address := mdataClient.getAddress(gtin)
-
-	// Construct TransactionHeader
-	rawTransactionHeader := transaction_pb2.TransactionHeader{
-		SignerPublicKey:  mdataClient.signer.GetPublicKey().AsHex(),
-		FamilyName:       constants.FAMILY_NAME,
-		FamilyVersion:    constants.FAMILY_VERSION,
-		Dependencies:     []string{}, // empty dependency list
-		Nonce:            strconv.Itoa(rand.Int()),
-		BatcherPublicKey: mdataClient.signer.GetPublicKey().AsHex(),
-		Inputs:           []string{address},
-		Outputs:          []string{address},
-		PayloadSha512:    Sha512HashValue(payload),
-	}
-	transactionHeader, err := proto.Marshal(&rawTransactionHeader)
-	if err != nil {
-		return "", fmt.Errorf("Unable to serialize transaction header: %v", err)
-	}
+// notFoundError is returned by sendRequest when the REST API has no state at
+// the requested address
+type notFoundError struct {
+	gtin string
+}
 
-	// Signature of TransactionHeader
-	transactionHeaderSignature := hex.EncodeToString(
-		mdataClient.signer.Sign(transactionHeader))
+func (err *notFoundError) Error() string {
+	return fmt.Sprintf("No such product: %s", err.gtin)
+}
 
-	// Construct Transaction
-	transaction := transaction_pb2.Transaction{
-		Header:          transactionHeader,
-		HeaderSignature: transactionHeaderSignature,
-		Payload:         []byte(payload),
+func (mdataClient MdataClient) sendTransaction(c MdataClientAction, wait uint) (string, error) {
+	transaction, err := mdataClient.newTransaction(c)
+	if err != nil {
+		return "", err
 	}
 
 	// Get BatchList
 	rawBatchList, err := mdataClient.createBatchList(
-		[]*transaction_pb2.Transaction{&transaction})
+		[]*transaction_pb2.Transaction{transaction})
 	if err != nil {
 		return "", fmt.Errorf("Unable to construct batch list: %v", err)
 	}
@@ -352,7 +576,7 @@ func (mdataClient MdataClient) sendTransaction(c MdataClientAction, wait uint) (
 		waitTime := uint(0)
 		startTime := time.Now()
 		response, err := mdataClient.sendRequest(
-			constants.BATCH_SUBMIT_API, batchList, constants.CONTENT_TYPE_OCTET_STREAM, gtin)
+			constants.BATCH_SUBMIT_API, batchList, constants.CONTENT_TYPE_OCTET_STREAM, c.gtin)
 		if err != nil {
 			return "", err
 		}
@@ -370,17 +594,75 @@ func (mdataClient MdataClient) sendTransaction(c MdataClientAction, wait uint) (
 	}
 
 	return mdataClient.sendRequest(
-		constants.BATCH_SUBMIT_API, batchList, constants.CONTENT_TYPE_OCTET_STREAM, gtin)
+		constants.BATCH_SUBMIT_API, batchList, constants.CONTENT_TYPE_OCTET_STREAM, c.gtin)
 }
 
-func (mdataClient MdataClient) getPrefix() string {
-	return Sha512HashValue(constants.FAMILY_NAME)[:constants.FAMILY_NAMESPACE_ADDRESS_LENGTH]
-}
+// newTransaction builds and signs the transaction carrying action c
+func (mdataClient MdataClient) newTransaction(c MdataClientAction) (*transaction_pb2.Transaction, error) {
+	var inputs, outputs []string
+	if c.org != nil || c.agent != nil {
+		// Organization, company prefix and agent records all live in the
+		// reserved range, the processor also reads the registrars setting
+		inputs = []string{model.ReservedPrefix, lifecycle.SettingAddress(model.RegistrarsSetting)}
+		outputs = []string{model.ReservedPrefix}
+	} else {
+		// The processor stores every product under its GTIN-14, so shorter GTINs
+		// are padded before the payload and address are built. Only creates
+		// need a valid check digit: products stored by family version 1.0
+		// may have any 14 digits.
+		normalize := gs1.NormalizeStoredGtin
+		if c.action == constants.VERB_CREATE {
+			normalize = gs1.NormalizeGtin
+		}
+		gtin, err := normalize(c.gtin)
+		if err != nil {
+			return nil, err
+		}
+		c.gtin = gtin
+
+		// The processor reads the owner of the GTIN's company prefix, the
+		// signer's agent record, the product's history and the product
+		// lifecycle and registrars settings. Listing only these addresses
+		// lets transactions on other products run in parallel.
+		address := model.ProductAddress(gtin)
+		inputs = append([]string{address}, model.CompanyPrefixAddresses(gtin)...)
+		inputs = append(inputs, model.AgentAddress(mdataClient.signer.GetPublicKey().AsHex()), model.HistoryPrefix(gtin),
+			lifecycle.SettingAddress(lifecycle.SettingKey), lifecycle.SettingAddress(model.RegistrarsSetting))
+		outputs = []string{address, model.HistoryPrefix(gtin)}
+	}
+
+	payload, err := c.serializePayload()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to serialize payload: %v", err)
+	}
 
-func (mdataClient MdataClient) getAddress(gtin string) string {
-	prefix := mdataClient.getPrefix()
-	productAddress := Sha512HashValue(gtin)[:constants.FAMILY_VERB_ADDRESS_LENGTH]
-	return prefix + productAddress
+	// Construct TransactionHeader
+	rawTransactionHeader := transaction_pb2.TransactionHeader{
+		SignerPublicKey:  mdataClient.signer.GetPublicKey().AsHex(),
+		FamilyName:       constants.FAMILY_NAME,
+		FamilyVersion:    constants.FAMILY_VERSION,
+		Dependencies:     c.dependencies,
+		Nonce:            strconv.Itoa(rand.Int()),
+		BatcherPublicKey: mdataClient.signer.GetPublicKey().AsHex(),
+		Inputs:           inputs,
+		Outputs:          outputs,
+		PayloadSha512:    Sha512HashValue(string(payload)),
+	}
+	transactionHeader, err := proto.Marshal(&rawTransactionHeader)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to serialize transaction header: %v", err)
+	}
+
+	// Signature of TransactionHeader
+	transactionHeaderSignature := hex.EncodeToString(
+		mdataClient.signer.Sign(transactionHeader))
+
+	// Construct Transaction
+	return &transaction_pb2.Transaction{
+		Header:          transactionHeader,
+		HeaderSignature: transactionHeaderSignature,
+		Payload:         payload,
+	}, nil
 }
 
 func (mdataClient MdataClient) createBatchList(
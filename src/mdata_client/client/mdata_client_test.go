@@ -0,0 +1,45 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/transaction_pb2"
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+func TestNewTransactionAddresses(t *testing.T) {
+	mdataClient, err := NewMdataClient("", "")
+	assert.Nil(t, err)
+	transaction, err := mdataClient.newTransaction(MdataClientAction{action: "update", gtin: "012345678905", attrs: map[string]string{"uom": "cases"}})
+	assert.Nil(t, err)
+	header := &transaction_pb2.TransactionHeader{}
+	assert.Nil(t, proto.Unmarshal(transaction.Header, header))
+
+	// Only the addresses the processor reads for the product are inputs, so
+	// transactions on other products and organizations do not conflict
+	gtin := "00012345678905"
+	assert.Equal(t, model.ProductAddress(gtin), header.Inputs[0])
+	assert.Subset(t, header.Inputs, model.CompanyPrefixAddresses(gtin))
+	assert.Contains(t, header.Inputs, model.AgentAddress(header.SignerPublicKey))
+	assert.Contains(t, header.Inputs, model.HistoryPrefix(gtin))
+	assert.NotContains(t, header.Inputs, model.ReservedPrefix)
+	assert.Equal(t, []string{model.ProductAddress(gtin), model.HistoryPrefix(gtin)}, header.Outputs)
+}
+
+func TestNewTransactionLegacyGtin(t *testing.T) {
+	mdataClient, err := NewMdataClient("", "")
+	assert.Nil(t, err)
+
+	// Family version 1.0 stored products under GTIN-14s with any check digit,
+	// so they can be changed but no longer created
+	transaction, err := mdataClient.newTransaction(MdataClientAction{action: "set", gtin: "00012345600013", state: "INACTIVE"})
+	assert.Nil(t, err)
+	header := &transaction_pb2.TransactionHeader{}
+	assert.Nil(t, proto.Unmarshal(transaction.Header, header))
+	assert.Equal(t, model.ProductAddress("00012345600013"), header.Inputs[0])
+
+	_, err = mdataClient.newTransaction(MdataClientAction{action: "create", gtin: "00012345600013", attrs: map[string]string{"uom": "cases"}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,202 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package client
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/constants"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"gopkg.in/yaml.v2"
+	"net/url"
+)
+
+// statePage is a response of the state REST API listing addresses
+type statePage struct {
+	Data []struct {
+		Address string `yaml:"address"`
+		Data    string `yaml:"data"`
+	} `yaml:"data"`
+	Head   string `yaml:"head"`
+	Paging struct {
+		Start        string `yaml:"start"`
+		Limit        uint   `yaml:"limit"`
+		NextPosition string `yaml:"next_position"`
+		Next         string `yaml:"next"`
+	} `yaml:"paging"`
+}
+
+// ProductPage is a page of the products in state
+type ProductPage struct {
+	Products []*model.Product
+	// Block the page was read at
+	Head string
+	// Paging position of the next page, empty on the last page
+	Next string
+}
+
+// List reads a page of products: those stored at up to limit addresses,
+// starting at the paging position start, or at the first product when start
+// is empty. Products iterates over every page.
+func (mdataClient MdataClient) List(start string, limit uint) (*ProductPage, error) {
+	return mdataClient.listPage("", start, limit)
+}
+
+// listPage reads a page of products at block head, or at the current head
+// when head is empty
+func (mdataClient MdataClient) listPage(head string, start string, limit uint) (*ProductPage, error) {
+	page, err := mdataClient.getStatePage(model.Namespace, head, start, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	productPage := &ProductPage{Head: page.Head}
+	if page.Paging.Next != "" {
+		productPage.Next = page.Paging.NextPosition
+	}
+	for _, entry := range page.Data {
+		// Organization, agent and history records share the namespace with
+		// products. State is listed by address and the reserved range sorts
+		// after every product address, so no product follows them.
+		if model.IsReserved(entry.Address) {
+			productPage.Next = ""
+			break
+		}
+		data, err := base64.StdEncoding.DecodeString(entry.Data)
+		if err != nil {
+			return nil, fmt.Errorf("Error decoding: %v", err)
+		}
+		products, err := model.DecodeProducts(data)
+		if err != nil {
+			return nil, err
+		}
+		productPage.Products = append(productPage.Products, products...)
+	}
+	return productPage, nil
+}
+
+// ProductIterator streams the products in state, reading a page at a time.
+// Every page is read at the block the first page was read at.
+//
+//	products := mdataClient.Products("", 0)
+//	for products.Next() {
+//		product := products.Product()
+//	}
+//	err := products.Err()
+type ProductIterator struct {
+	mdataClient MdataClient
+	head        string
+	start       string
+	limit       uint
+	page        []*model.Product
+	product     *model.Product
+	err         error
+	done        bool
+}
+
+// Products returns an iterator over the products in state from the paging
+// position start, or from the first product when start is empty, reading
+// limit addresses per request. A limit of 0 reads the most the REST API
+// returns.
+func (mdataClient MdataClient) Products(start string, limit uint) *ProductIterator {
+	if limit == 0 {
+		limit = constants.STATE_PAGE_LIMIT
+	}
+	return &ProductIterator{mdataClient: mdataClient, start: start, limit: limit}
+}
+
+// Next advances to the next product, reading the next page when the current
+// one is exhausted. It returns false at the end of state or on an error.
+func (it *ProductIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			it.product = nil
+			return false
+		}
+		page, err := it.mdataClient.listPage(it.head, it.start, it.limit)
+		if err != nil {
+			it.err = err
+			continue
+		}
+		it.head = page.Head
+		it.start = page.Next
+		it.done = page.Next == ""
+		it.page = page.Products
+	}
+	it.product, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Product returns the product Next advanced to
+func (it *ProductIterator) Product() *model.Product {
+	return it.product
+}
+
+// Err returns the error that ended the iteration, if any
+func (it *ProductIterator) Err() error {
+	return it.err
+}
+
+// Head returns the block the products are read at, once a page was read
+func (it *ProductIterator) Head() string {
+	return it.head
+}
+
+// ForEachProduct calls fn with every product in state, reading the state API
+// a page at a time. Products are read at block head, or at the current head
+// when head is empty; the block read is returned so a later call can see the
+// same products.
+func (mdataClient MdataClient) ForEachProduct(head string, fn func(*model.Product) error) (string, error) {
+	products := mdataClient.Products("", 0)
+	products.head = head
+	for products.Next() {
+		err := fn(products.Product())
+		if err != nil {
+			return "", err
+		}
+	}
+	if products.Err() != nil {
+		return "", products.Err()
+	}
+	return products.Head(), nil
+}
+
+// getStatePage reads up to limit entries of state under address, starting at
+// the paging position start
+func (mdataClient MdataClient) getStatePage(address string, head string, start string, limit uint) (*statePage, error) {
+	query := url.Values{}
+	query.Set("address", address)
+	query.Set("limit", fmt.Sprint(limit))
+	if head != "" {
+		query.Set("head", head)
+	}
+	if start != "" {
+		query.Set("start", start)
+	}
+	response, err := mdataClient.sendRequest(constants.STATE_API+"?"+query.Encode(), []byte{}, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	page := &statePage{}
+	err = yaml.Unmarshal([]byte(response), page)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading response: %v", err)
+	}
+	return page, nil
+}
@@ -0,0 +1,86 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package agent
+
+import (
+	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+)
+
+type AgentArgs struct {
+	Args struct {
+		PublicKey string `positional-arg-name:"pubkey" required:"true" description:"Specify the public key of the agent"`
+	} `positional-args:"true"`
+	Roles []string `long:"role" choice:"product.create" choice:"product.update" choice:"product.delete" choice:"product.set_state" choice:"product.transfer" description:"Specify a role granted to the agent, may be repeated"`
+}
+
+type Agent struct {
+	Create struct {
+		AgentArgs
+		OrgId string `long:"org" required:"true" description:"Identify the organization the agent acts for"`
+	} `command:"create" description:"Adds an agent to an organization" long-description:"Sends an mdata transaction adding <pubkey> as an agent of --org with the --role roles. The signer must be an admin of the organization."`
+	Update  AgentArgs `command:"update" description:"Assigns roles to an agent" long-description:"Sends an mdata transaction replacing the roles of agent <pubkey> with the --role roles. The signer must be an admin of the agent's organization."`
+	Url     string    `long:"url" description:"Specify URL of REST API"`
+	Keyfile string    `long:"keyfile" description:"Identify file containing user's private key"`
+	Wait    uint      `long:"wait" description:"Set time, in seconds, to wait for transaction to commit"`
+
+	cmd *flags.Command
+}
+
+func (args *Agent) Name() string {
+	return "agent"
+}
+
+func (args *Agent) KeyfilePassed() string {
+	return args.Keyfile
+}
+
+func (args *Agent) UrlPassed() string {
+	return args.Url
+}
+
+func (args *Agent) Register(parent *flags.Command) error {
+	cmd, err := parent.AddCommand(args.Name(), "Manages agents", "Adds agents to organizations and assigns the roles allowing them to maintain the organization's products.", args)
+	if err != nil {
+		return err
+	}
+	args.cmd = cmd
+	return nil
+}
+
+func (args *Agent) Run() error {
+	mdataClient, err := client.GetClient(args, true)
+	if err != nil {
+		return err
+	}
+
+	switch args.cmd.Active.Name {
+	case "create":
+		_, err = mdataClient.CreateAgent(client.Agent{
+			PublicKey: args.Create.Args.PublicKey,
+			OrgId:     args.Create.OrgId,
+			Roles:     args.Create.Roles,
+		}, args.Wait)
+	case "update":
+		_, err = mdataClient.UpdateAgent(client.Agent{
+			PublicKey: args.Update.Args.PublicKey,
+			Roles:     args.Update.Roles,
+		}, args.Wait)
+	}
+	return err
+}
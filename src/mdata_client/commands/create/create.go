@@ -19,6 +19,7 @@ package create
 
 import (
 	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/gs1"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
 )
 
@@ -58,6 +59,12 @@ func (args *Create) Run() error {
 	attributes := args.Attributes
 	wait := args.Wait
 
+	// Reject a bad GTIN before signing a transaction the processor would refuse
+	err := gs1.ValidateGtin(gtin)
+	if err != nil {
+		return err
+	}
+
 	mdataClient, err := client.GetClient(args, true)
 	if err != nil {
 		return err
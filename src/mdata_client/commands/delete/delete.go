@@ -19,6 +19,7 @@ package delete
 
 import (
 	"github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/gs1"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
 )
 
@@ -56,6 +57,13 @@ func (args *Delete) Run() error {
 	gtin := args.Args.Gtin
 	wait := args.Wait
 
+	// Reject a bad GTIN before signing a transaction the processor would refuse,
+	// accepting the 14-digit GTINs family version 1.0 stored unchecked
+	_, err := gs1.NormalizeStoredGtin(gtin)
+	if err != nil {
+		return err
+	}
+
 	mdataClient, err := client.GetClient(args, true)
 	if err != nil {
 		return err
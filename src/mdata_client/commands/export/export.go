@@ -0,0 +1,176 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/output"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"io"
+	"os"
+	"sort"
+)
+
+type Export struct {
+	Format string `long:"format" choice:"csv" choice:"json" choice:"ndjson" default:"csv" description:"Specify the output format"`
+	Output string `long:"output" short:"o" description:"Write the products to a file instead of standard output"`
+	Url    string `long:"url" description:"Specify URL of REST API"`
+}
+
+func (args *Export) Name() string {
+	return "export"
+}
+
+func (args *Export) KeyfilePassed() string {
+	return ""
+}
+
+func (args *Export) UrlPassed() string {
+	return args.Url
+}
+
+func (args *Export) Register(parent *flags.Command) error {
+	_, err := parent.AddCommand(args.Name(), "Exports all mdata products",
+		"Writes every product in mdata state as CSV, with a column per attribute, as a JSON array or as one JSON object per line.", args)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (args *Export) Run() error {
+	// Construct client
+	mdataClient, err := client.GetClient(args, false)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if args.Output != "" {
+		file, err = os.Create(args.Output)
+		if err != nil {
+			return err
+		}
+		out = file
+	}
+	writer := bufio.NewWriter(out)
+
+	var count int
+	switch args.Format {
+	case "csv":
+		count, err = writeCsv(mdataClient, writer)
+	case "json":
+		count, err = writeJson(mdataClient, writer)
+	case "ndjson":
+		count, err = writeNdjson(mdataClient, writer)
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if file != nil {
+		closeErr := file.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			// Leave no partial export behind
+			os.Remove(args.Output)
+			return err
+		}
+		fmt.Printf("Exported %v products to %v\n", count, args.Output)
+	}
+	return err
+}
+
+// writeCsv writes a gtin and a state column followed by a column per attribute
+// key, the layout mdata import reads. The attribute keys are collected in a
+// first pass over state, and the second pass reads the same block.
+func writeCsv(mdataClient client.MdataClient, out io.Writer) (int, error) {
+	seen := make(map[string]bool)
+	head, err := mdataClient.ForEachProduct("", func(product *model.Product) error {
+		for key := range product.Attributes {
+			seen[key] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	var keys []string
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writer, err := output.NewCsvWriter(out, keys)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	_, err = mdataClient.ForEachProduct(head, func(product *model.Product) error {
+		count++
+		return writer.Write(product)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, writer.Flush()
+}
+
+// writeJson writes the products as a JSON array, one product to a line
+func writeJson(mdataClient client.MdataClient, out io.Writer) (int, error) {
+	count := 0
+	_, err := mdataClient.ForEachProduct("", func(product *model.Product) error {
+		data, err := json.Marshal(product)
+		if err != nil {
+			return err
+		}
+		separator := ",\n"
+		if count == 0 {
+			separator = "[\n"
+		}
+		count++
+		_, err = fmt.Fprintf(out, "%s%s", separator, data)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		_, err = fmt.Fprintln(out, "[]")
+	} else {
+		_, err = fmt.Fprintln(out, "\n]")
+	}
+	return count, err
+}
+
+// writeNdjson writes the products as newline delimited JSON
+func writeNdjson(mdataClient client.MdataClient, out io.Writer) (int, error) {
+	encoder := json.NewEncoder(out)
+	count := 0
+	_, err := mdataClient.ForEachProduct("", func(product *model.Product) error {
+		count++
+		return encoder.Encode(product)
+	})
+	return count, err
+}
@@ -0,0 +1,134 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package history
+
+import (
+	"fmt"
+	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/protobuf/history_pb2"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type History struct {
+	Args struct {
+		Gtin string `positional-arg-name:"gtin" required:"true" description:"Identify the gtin of the product"`
+	} `positional-args:"true"`
+	Url string `long:"url" description:"Specify URL of REST API"`
+}
+
+func (args *History) Name() string {
+	return "history"
+}
+
+func (args *History) KeyfilePassed() string {
+	return ""
+}
+
+func (args *History) UrlPassed() string {
+	return args.Url
+}
+
+func (args *History) Register(parent *flags.Command) error {
+	_, err := parent.AddCommand(args.Name(), "Displays the change history of a product", "Shows every change recorded on chain for <gtin>, oldest first.", args)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (args *History) Run() error {
+	// Construct client
+	mdataClient, err := client.GetClient(args, false)
+	if err != nil {
+		return err
+	}
+	entries, err := mdataClient.History(args.Args.Gtin)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tTIME\tACTION\tSIGNER\tSTATE\tCHANGES")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n",
+			entry.GetVersion(),
+			formatTimestamp(entry.GetTimestamp()),
+			entry.GetAction(),
+			shorten(entry.GetSigner()),
+			formatState(entry),
+			formatChanges(entry))
+	}
+	return w.Flush()
+}
+
+func formatTimestamp(timestamp int64) string {
+	// Transactions in the comma separated payload carry no timestamp
+	if timestamp == 0 {
+		return "-"
+	}
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+}
+
+func formatState(entry *history_pb2.HistoryEntry) string {
+	if entry.GetPreviousState() == entry.GetState() {
+		return entry.GetState()
+	}
+	previous, state := entry.GetPreviousState(), entry.GetState()
+	if previous == "" {
+		previous = "-"
+	}
+	if state == "" {
+		state = "-"
+	}
+	return previous + " -> " + state
+}
+
+// formatChanges lists added attributes as +key=value, changed ones as
+// ~key=old->new and removed ones as -key=old, followed by any new owners
+func formatChanges(entry *history_pb2.HistoryEntry) string {
+	var changes []string
+	for _, change := range entry.GetChanges() {
+		switch change.GetKind() {
+		case history_pb2.AttributeChange_ADDED:
+			changes = append(changes, fmt.Sprintf("+%v=%v", change.GetKey(), change.GetNewValue()))
+		case history_pb2.AttributeChange_CHANGED:
+			changes = append(changes, fmt.Sprintf("~%v=%v->%v", change.GetKey(), change.GetOldValue(), change.GetNewValue()))
+		case history_pb2.AttributeChange_REMOVED:
+			changes = append(changes, fmt.Sprintf("-%v=%v", change.GetKey(), change.GetOldValue()))
+		}
+	}
+	if len(entry.GetOwners()) > 0 {
+		var owners []string
+		for _, owner := range entry.GetOwners() {
+			owners = append(owners, shorten(owner))
+		}
+		changes = append(changes, "owners="+strings.Join(owners, ","))
+	}
+	return strings.Join(changes, " ")
+}
+
+func shorten(publicKey string) string {
+	if len(publicKey) > 8 {
+		return publicKey[:8] + "..."
+	}
+	return publicKey
+}
@@ -0,0 +1,101 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package importcsv
+
+import (
+	"fmt"
+	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/constants"
+	"os"
+	"strconv"
+)
+
+type Import struct {
+	Args struct {
+		File string `positional-arg-name:"file" required:"true" description:"CSV file with a gtin column, an optional state column and a column per attribute"`
+	} `positional-args:"true"`
+	BatchSize uint   `long:"batch-size" default:"100" description:"Set the number of transactions sent in one batch"`
+	Url       string `long:"url" description:"Specify URL of REST API"`
+	Keyfile   string `long:"keyfile" description:"Identify file containing user's private key"`
+	Wait      uint   `long:"wait" default:"60" description:"Set time, in seconds, to wait for the batches to commit"`
+}
+
+func (args *Import) Name() string {
+	return "import"
+}
+
+func (args *Import) KeyfilePassed() string {
+	return args.Keyfile
+}
+
+func (args *Import) UrlPassed() string {
+	return args.Url
+}
+
+func (args *Import) Register(parent *flags.Command) error {
+	_, err := parent.AddCommand(args.Name(), "Creates products from a CSV file",
+		"Sends mdata transactions creating a product for each row of <file>. The header row names the columns: "+
+			"gtin is required, state is optional and every other column is an attribute.", args)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (args *Import) Run() error {
+	if args.BatchSize < 1 || args.BatchSize > constants.MAX_BATCH_TRANSACTIONS {
+		return fmt.Errorf("Batch size must be between 1 and %v", constants.MAX_BATCH_TRANSACTIONS)
+	}
+
+	file, err := os.Open(args.Args.File)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	rows, err := client.ReadImportFile(file)
+	if err != nil {
+		return err
+	}
+
+	// Construct client
+	mdataClient, err := client.GetClient(args, true)
+	if err != nil {
+		return err
+	}
+	results, err := mdataClient.Import(rows, args.BatchSize, args.Wait)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		line := args.Args.File + ":" + strconv.Itoa(result.Row.Line)
+		if result.Status == client.ImportCommitted {
+			fmt.Printf("%v: %v %v\n", line, result.Row.Gtin, result.Status)
+			continue
+		}
+		failed++
+		fmt.Printf("%v: %v %v: %v\n", line, result.Row.Gtin, result.Status, result.Message)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%v of %v products were not imported", failed, len(results))
+	}
+	fmt.Printf("Imported %v products\n", len(results))
+	return nil
+}
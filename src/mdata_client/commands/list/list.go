@@ -21,11 +21,21 @@ import (
 	"fmt"
 	flags "github.com/jessevdk/go-flags"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
-	"strings"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/constants"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/output"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"os"
 )
 
 type List struct {
-	Url string `long:"url" description:"Specify URL of REST API"`
+	Format     string            `long:"format" choice:"table" choice:"json" choice:"yaml" choice:"csv" default:"table" description:"Specify the output format"`
+	Limit      uint              `long:"limit" description:"Show one page of products, read from this many state addresses"`
+	Start      string            `long:"start" description:"Start at this paging position, printed after a page of products"`
+	State      []string          `long:"state" description:"Only show products in this state, may be repeated to allow several"`
+	Attr       map[string]string `long:"attr" key-value-delimiter:"=" description:"Only show products with the attribute key=value, may be repeated"`
+	AttrExists []string          `long:"attr-exists" description:"Only show products having the attribute key, may be repeated"`
+	GtinPrefix string            `long:"gtin-prefix" description:"Only show products whose GTIN-14 starts with this prefix"`
+	Url        string            `long:"url" description:"Specify URL of REST API"`
 }
 
 func (args *List) Name() string {
@@ -49,29 +59,49 @@ func (args *List) Register(parent *flags.Command) error {
 }
 
 func (args *List) Run() error {
+	if args.Limit > constants.STATE_PAGE_LIMIT {
+		return fmt.Errorf("Limit must be at most %v", constants.STATE_PAGE_LIMIT)
+	}
 
-	//TODO: Check back here after List() has been defined in mdataClient
 	// Construct client
 	mdataClient, err := client.GetClient(args, false)
 	if err != nil {
 		return err
 	}
-	products, err := mdataClient.List()
-	if err != nil {
-		return err
-	}
 
-	fmt.Printf("%-15v\t%-40v\t%-10v\t\n", "GTIN", "ATTRIBUTES", "STATE")
+	filter := &client.ProductFilter{
+		States:          args.State,
+		Attributes:      args.Attr,
+		AttributesExist: args.AttrExists,
+		GtinPrefix:      args.GtinPrefix,
+	}
 
-	for _, product := range products {
-		for _, str := range strings.Split(product, "|") {
-			parts := strings.Split(str, ",")
-			gtin := parts[0]
-			attrs := parts[1 : len(parts)-1]
-			state := parts[len(parts)-1]
+	// With a limit only one page is shown, along with the position of the next
+	if args.Limit > 0 {
+		page, err := mdataClient.List(args.Start, args.Limit)
+		if err != nil {
+			return err
+		}
+		err = output.WriteProducts(os.Stdout, args.Format, filter.Filter(page.Products))
+		if err != nil {
+			return err
+		}
+		if page.Next != "" {
+			// Standard error keeps the structured formats parseable
+			fmt.Fprintf(os.Stderr, "More products follow, continue with: mdata list --limit %v --start %v\n", args.Limit, page.Next)
+		}
+		return nil
+	}
 
-			fmt.Printf("%-v\t%-40v\t%-v\t\n", gtin, attrs, state)
+	var products []*model.Product
+	iterator := mdataClient.Products(args.Start, 0)
+	for iterator.Next() {
+		if filter.Match(iterator.Product()) {
+			products = append(products, iterator.Product())
 		}
 	}
-	return nil
+	if iterator.Err() != nil {
+		return iterator.Err()
+	}
+	return output.WriteProducts(os.Stdout, args.Format, products)
 }
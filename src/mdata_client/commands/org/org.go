@@ -0,0 +1,91 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package org
+
+import (
+	"fmt"
+	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+)
+
+type OrgArgs struct {
+	Args struct {
+		OrgId string `positional-arg-name:"org_id" required:"true" description:"Identify the organization"`
+	} `positional-args:"true"`
+	OrgName  string   `long:"name" description:"Specify the organization's display name"`
+	Admins   []string `long:"admin" description:"Specify an admin public key, may be repeated"`
+	Prefixes []string `long:"prefix" description:"Specify a GS1 company prefix owned by the organization, may be repeated"`
+}
+
+type Org struct {
+	Create  OrgArgs `command:"create" description:"Creates an organization" long-description:"Sends an mdata transaction to create <org_id> owning the --prefix company prefixes. The signer must be listed by the mdata.org.registrars setting. Admins default to the signer's public key."`
+	Update  OrgArgs `command:"update" description:"Updates an organization" long-description:"Sends an mdata transaction to replace the name, admins and company prefixes of <org_id>. Changing the name or admins requires an admin, changing the company prefixes a registrar."`
+	Url     string  `long:"url" description:"Specify URL of REST API"`
+	Keyfile string  `long:"keyfile" description:"Identify file containing user's private key"`
+	Wait    uint    `long:"wait" description:"Set time, in seconds, to wait for transaction to commit"`
+
+	cmd *flags.Command
+}
+
+func (args *Org) Name() string {
+	return "org"
+}
+
+func (args *Org) KeyfilePassed() string {
+	return args.Keyfile
+}
+
+func (args *Org) UrlPassed() string {
+	return args.Url
+}
+
+func (args *Org) Register(parent *flags.Command) error {
+	cmd, err := parent.AddCommand(args.Name(), "Manages organizations", "Creates and updates the organizations owning GS1 company prefixes.", args)
+	if err != nil {
+		return err
+	}
+	args.cmd = cmd
+	return nil
+}
+
+func (args *Org) Run() error {
+	mdataClient, err := client.GetClient(args, true)
+	if err != nil {
+		return err
+	}
+
+	switch args.cmd.Active.Name {
+	case "create":
+		_, err = mdataClient.CreateOrg(args.Create.organization(), args.Wait)
+	case "update":
+		if len(args.Update.Admins) == 0 {
+			return fmt.Errorf("Update requires at least one --admin")
+		}
+		_, err = mdataClient.UpdateOrg(args.Update.organization(), args.Wait)
+	}
+	return err
+}
+
+func (args *OrgArgs) organization() client.Organization {
+	return client.Organization{
+		OrgId:           args.Args.OrgId,
+		Name:            args.OrgName,
+		Admins:          args.Admins,
+		CompanyPrefixes: args.Prefixes,
+	}
+}
@@ -0,0 +1,89 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package patch
+
+import (
+	"fmt"
+	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/gs1"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"strings"
+)
+
+type Patch struct {
+	Args struct {
+		Gtin       string   `positional-arg-name:"gtin" required:"true" description:"Identify the gtin of the product to patch"`
+		Attributes []string `positional-arg-name:"key:value" description:"Specify key:value pairs to add or change"`
+	} `positional-args:"true"`
+	Remove  []string `long:"remove" short:"r" description:"Specify an attribute key to remove, may be repeated"`
+	Url     string   `long:"url" description:"Specify URL of REST API"`
+	Keyfile string   `long:"keyfile" description:"Identify file containing user's private key"`
+	Wait    uint     `long:"wait" description:"Set time, in seconds, to wait for transaction to commit"`
+}
+
+func (args *Patch) Name() string {
+	return "patch"
+}
+
+func (args *Patch) KeyfilePassed() string {
+	return args.Keyfile
+}
+
+func (args *Patch) UrlPassed() string {
+	return args.Url
+}
+
+func (args *Patch) Register(parent *flags.Command) error {
+	_, err := parent.AddCommand(args.Name(), "Patches a product", "Sends an mdata transaction to set the <key:value> attributes of <gtin> and remove the --remove keys, keeping all other attributes.", args)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (args *Patch) Run() error {
+	// Construct client
+	gtin := args.Args.Gtin
+	wait := args.Wait
+
+	attributes := make(map[string]string)
+	for _, pair := range args.Args.Attributes {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("Invalid attribute '%v', expected key:value", pair)
+		}
+		attributes[parts[0]] = parts[1]
+	}
+	if len(attributes) == 0 && len(args.Remove) == 0 {
+		return fmt.Errorf("Patch requires key:value attributes or --remove keys")
+	}
+
+	// Reject a bad GTIN before signing a transaction the processor would refuse,
+	// accepting the 14-digit GTINs family version 1.0 stored unchecked
+	_, err := gs1.NormalizeStoredGtin(gtin)
+	if err != nil {
+		return err
+	}
+
+	mdataClient, err := client.GetClient(args, true)
+	if err != nil {
+		return err
+	}
+	_, err = mdataClient.Patch(gtin, attributes, args.Remove, wait)
+	return err
+}
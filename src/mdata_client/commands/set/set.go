@@ -19,13 +19,14 @@ package set
 
 import (
 	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/gs1"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
 )
 
 type Set struct {
 	Args struct {
 		Gtin  string `positional-arg-name:"gtin" required:"true" description:"Identify the gtin of the product to set state"`
-		State string `positional-arg-name:"state" required:"true" description:"Specify the state to set the <gtin>: ACTIVE, INACTIVE, DISCONTINUED" choice:"INACTIVE" choice:"ACTIVE" choice:"DISCONTINUED"`
+		State string `positional-arg-name:"state" required:"true" description:"Specify the state to set the <gtin>, one of the states of the mdata.product.states setting (default ACTIVE, INACTIVE, DISCONTINUED)"`
 	} `positional-args:"true"`
 	Url     string `long:"url" description:"Specify URL of REST API"`
 	Keyfile string `long:"keyfile" description:"Identify file containing user's private key"`
@@ -58,10 +59,28 @@ func (args *Set) Run() error {
 	state := args.Args.State
 	wait := args.Wait
 
+	// Reject a bad GTIN before signing a transaction the processor would refuse,
+	// accepting the 14-digit GTINs family version 1.0 stored unchecked
+	_, err := gs1.NormalizeStoredGtin(gtin)
+	if err != nil {
+		return err
+	}
+
 	mdataClient, err := client.GetClient(args, true)
 	if err != nil {
 		return err
 	}
+
+	// The states are configured on chain, so check against the live setting
+	productLifecycle, err := mdataClient.GetLifecycle()
+	if err != nil {
+		return err
+	}
+	err = productLifecycle.ValidateState(state)
+	if err != nil {
+		return err
+	}
+
 	_, err = mdataClient.Set(gtin, state, wait)
 	return err
 }
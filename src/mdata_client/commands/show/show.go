@@ -18,17 +18,19 @@
 package show
 
 import (
-	"fmt"
 	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/gs1"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
-	"strings"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/output"
+	"os"
 )
 
 type Show struct {
 	Args struct {
 		Gtin string `positional-arg-name:"gtin" required:"true" description:"Identify the gtin of the product to create"`
 	} `positional-args:"true"`
-	Url string `long:"url" description:"Specify URL of REST API"`
+	Format string `long:"format" choice:"table" choice:"json" choice:"yaml" choice:"csv" default:"table" description:"Specify the output format"`
+	Url    string `long:"url" description:"Specify URL of REST API"`
 }
 
 func (args *Show) Name() string {
@@ -52,26 +54,19 @@ func (args *Show) Register(parent *flags.Command) error {
 }
 
 func (args *Show) Run() error {
-	//TODO: Check back here after mdataClient.Show() has been defined
+	// Products are stored under their GTIN-14, so shorter GTINs are padded to match
+	gtin, err := gs1.NormalizeStoredGtin(args.Args.Gtin)
+	if err != nil {
+		return err
+	}
 	// Construct client
-	gtin := args.Args.Gtin
 	mdataClient, err := client.GetClient(args, false)
 	if err != nil {
 		return err
 	}
-	products, err := mdataClient.Show(gtin)
+	product, err := mdataClient.Show(gtin)
 	if err != nil {
 		return err
 	}
-
-	productMap := make(map[string][]string)
-
-	for _, product := range strings.Split(products, "|") {
-		parts := strings.Split(product, ",")
-		gtin := parts[0]
-		productMap[gtin] = parts[1:]
-	}
-
-	fmt.Println(productMap[gtin])
-	return nil
+	return output.WriteProduct(os.Stdout, args.Format, product)
 }
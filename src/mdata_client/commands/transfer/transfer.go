@@ -0,0 +1,74 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package transfer
+
+import (
+	"github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/gs1"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+)
+
+type Transfer struct {
+	Args struct {
+		Gtin     string `positional-arg-name:"gtin" required:"true" description:"Identify the gtin of the product to transfer"`
+		NewOwner string `positional-arg-name:"pubkey" required:"true" description:"Specify the public key of the new owner"`
+	} `positional-args:"true"`
+	Url     string `long:"url" description:"Specify URL of REST API"`
+	Keyfile string `long:"keyfile" description:"Identify file containing user's private key"`
+	Wait    uint   `long:"wait" description:"Set time, in seconds, to wait for transaction to commit"`
+}
+
+func (args *Transfer) Name() string {
+	return "transfer"
+}
+
+func (args *Transfer) KeyfilePassed() string {
+	return args.Keyfile
+}
+
+func (args *Transfer) UrlPassed() string {
+	return args.Url
+}
+
+func (args *Transfer) Register(parent *flags.Command) error {
+	_, err := parent.AddCommand(args.Name(), "Transfers a product", "Sends an mdata transaction making <pubkey> the only owner of <gtin>.", args)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (args *Transfer) Run() error {
+	// Construct client
+	gtin := args.Args.Gtin
+	wait := args.Wait
+
+	// Reject a bad GTIN before signing a transaction the processor would refuse,
+	// accepting the 14-digit GTINs family version 1.0 stored unchecked
+	_, err := gs1.NormalizeStoredGtin(gtin)
+	if err != nil {
+		return err
+	}
+
+	mdataClient, err := client.GetClient(args, true)
+	if err != nil {
+		return err
+	}
+	_, err = mdataClient.Transfer(gtin, args.Args.NewOwner, wait)
+	return err
+}
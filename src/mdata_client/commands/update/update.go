@@ -19,6 +19,7 @@ package update
 
 import (
 	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/gs1"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
 )
 
@@ -30,6 +31,7 @@ type Update struct {
 	Url        string            `long:"url" description:"Specify URL of REST API"`
 	Keyfile    string            `long:"keyfile" description:"Identify file containing user's private key"`
 	Wait       uint              `long:"wait" description:"Set time, in seconds, to wait for transaction to commit"`
+	IfVersion  bool              `long:"if-version" description:"Reject the update if the product changes between reading its version and committing"`
 }
 
 func (args *Update) Name() string {
@@ -58,10 +60,17 @@ func (args *Update) Run() error {
 	attributes := args.Attributes
 	wait := args.Wait
 
+	// Reject a bad GTIN before signing a transaction the processor would refuse,
+	// accepting the 14-digit GTINs family version 1.0 stored unchecked
+	_, err := gs1.NormalizeStoredGtin(gtin)
+	if err != nil {
+		return err
+	}
+
 	mdataClient, err := client.GetClient(args, true)
 	if err != nil {
 		return err
 	}
-	_, err = mdataClient.Update(gtin, attributes, wait)
+	_, err = mdataClient.Update(gtin, attributes, args.IfVersion, wait)
 	return err
 }
@@ -0,0 +1,156 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/constants"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+type Watch struct {
+	Gtin        string   `long:"gtin" description:"Only show changes to the product with this gtin"`
+	EventType   []string `long:"event-type" choice:"product_created" choice:"product_updated" choice:"state_changed" choice:"product_deleted" description:"Only show events of this type, may be repeated"`
+	Format      string   `long:"format" choice:"text" choice:"json" default:"text" description:"Specify the output format, json prints one object per line"`
+	LastBlockId string   `long:"last-block-id" description:"Start with the changes committed after this block"`
+	Connect     string   `short:"C" long:"connect" description:"Specify the validator component endpoint"`
+}
+
+func (args *Watch) Name() string {
+	return "watch"
+}
+
+func (args *Watch) KeyfilePassed() string {
+	return ""
+}
+
+func (args *Watch) UrlPassed() string {
+	return ""
+}
+
+func (args *Watch) Register(parent *flags.Command) error {
+	_, err := parent.AddCommand(args.Name(), "Displays product changes as they are committed",
+		"Subscribes to the validator's mdata events and prints each product change as its block is committed. "+
+			"On interrupt the id of the last block seen is printed, pass it to --last-block-id to resume without missing a change.", args)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (args *Watch) Run() error {
+	url := args.Connect
+	if url == "" {
+		url = constants.DEFAULT_VALIDATOR_URL
+	}
+	filter := client.EventFilter{Gtin: args.Gtin}
+	for _, eventType := range args.EventType {
+		filter.EventTypes = append(filter.EventTypes, "mdata/"+eventType)
+	}
+
+	var knownBlockIds []string
+	if args.LastBlockId != "" {
+		knownBlockIds = []string{args.LastBlockId}
+	}
+	stream, err := client.Subscribe(url, filter, knownBlockIds)
+	if err != nil {
+		return err
+	}
+
+	// Next blocks until a block is committed, so blocks are read in the
+	// background while waiting for an interrupt. The stream is left open on
+	// return, as it cannot be closed while Next waits; exiting disconnects
+	// from the validator, which drops the subscription.
+	blocks := make(chan *client.BlockEvents)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			block, err := stream.Next()
+			if err != nil {
+				errs <- err
+				return
+			}
+			blocks <- block
+		}
+	}()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
+	lastBlockId := args.LastBlockId
+	for {
+		select {
+		case block := <-blocks:
+			for _, event := range block.Events {
+				err = args.print(event)
+				if err != nil {
+					return err
+				}
+			}
+			lastBlockId = block.BlockId
+		case err := <-errs:
+			return err
+		case <-interrupt:
+			if lastBlockId != "" {
+				fmt.Fprintf(os.Stderr, "Last block: %v\n", lastBlockId)
+			}
+			return nil
+		}
+	}
+}
+
+func (args *Watch) print(event *client.ProductEvent) error {
+	if args.Format == "json" {
+		return json.NewEncoder(os.Stdout).Encode(event)
+	}
+
+	state := event.State
+	if event.PreviousState != "" {
+		state = event.PreviousState + " -> " + state
+	}
+	var keys []string
+	for key := range event.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var attributes []string
+	for _, key := range keys {
+		attributes = append(attributes, key+"="+event.Attributes[key])
+	}
+	_, err := fmt.Printf("%v %v %v %v %v %v\n",
+		event.BlockNum,
+		strings.TrimPrefix(event.EventType, "mdata/"),
+		event.Gtin,
+		state,
+		shorten(event.Signer),
+		strings.Join(attributes, ","))
+	return err
+}
+
+func shorten(publicKey string) string {
+	if len(publicKey) > 8 {
+		return publicKey[:8] + "..."
+	}
+	return publicKey
+}
@@ -20,22 +20,35 @@ package constants
 const (
 	// String literals
 	FAMILY_NAME          string = "mdata"
-	FAMILY_VERSION       string = "1.0"
+	FAMILY_VERSION       string = "2.0"
 	DISTRIBUTION_NAME    string = "sawtooth-mdata"
 	DISTRIBUTION_VERSION string = ""
 	DEFAULT_URL          string = "http://127.0.0.1:8008"
+	// Validator endpoint event subscriptions connect to
+	DEFAULT_VALIDATOR_URL string = "tcp://127.0.0.1:4004"
 	// Verbs
-	VERB_CREATE    string = "create"
-	VERB_UPDATE    string = "update"
-	VERB_DELETE    string = "delete"
-	VERB_SET_STATE string = "set"
+	VERB_CREATE       string = "create"
+	VERB_UPDATE       string = "update"
+	VERB_DELETE       string = "delete"
+	VERB_SET_STATE    string = "set"
+	VERB_PATCH        string = "patch"
+	VERB_UNSET        string = "unset"
+	VERB_TRANSFER     string = "transfer"
+	VERB_ORG_CREATE   string = "org_create"
+	VERB_ORG_UPDATE   string = "org_update"
+	VERB_AGENT_CREATE string = "agent_create"
+	VERB_AGENT_UPDATE string = "agent_update"
 	// APIs
 	BATCH_SUBMIT_API string = "batches"
 	BATCH_STATUS_API string = "batch_statuses"
 	STATE_API        string = "state"
 	// Content types
 	CONTENT_TYPE_OCTET_STREAM string = "application/octet-stream"
+	CONTENT_TYPE_JSON         string = "application/json"
 	// Integer literals
-	FAMILY_NAMESPACE_ADDRESS_LENGTH uint = 6
-	FAMILY_VERB_ADDRESS_LENGTH      uint = 64
+	// Most transactions import puts in one batch, keeping batches within the
+	// size validators accept
+	MAX_BATCH_TRANSACTIONS uint = 100
+	// Entries read per request when walking state, the most the REST API returns
+	STATE_PAGE_LIMIT uint = 1000
 )
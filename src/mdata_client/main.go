@@ -22,12 +22,21 @@ import (
 	"github.com/hyperledger/sawtooth-sdk-go/logging"
 	flags "github.com/jessevdk/go-flags"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/commands"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/agent"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/create"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/delete"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/export"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/history"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/importcsv"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/list"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/org"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/patch"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/set"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/show"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/transfer"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/unset"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/update"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/commands/watch"
 	"github.com/tross-tyson/mdata_go/src/mdata_client/constants"
 	"os"
 )
@@ -63,11 +72,20 @@ func main() {
 	// Add sub-commands
 	commands := []commands.Command{
 		&create.Create{},
+		&importcsv.Import{},
 		&delete.Delete{},
 		&update.Update{},
+		&patch.Patch{},
+		&unset.Unset{},
 		&set.Set{},
+		&transfer.Transfer{},
 		&show.Show{},
 		&list.List{},
+		&export.Export{},
+		&history.History{},
+		&watch.Watch{},
+		&org.Org{},
+		&agent.Agent{},
 	}
 
 	for _, cmd := range commands {
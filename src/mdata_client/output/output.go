@@ -0,0 +1,181 @@
+/**
+ * Copyright 2018 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+// Package output renders products for the list and show commands
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"gopkg.in/yaml.v2"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats. JSON and YAML use the field names of model.Product; CSV
+// has a gtin and a state column followed by a column per attribute, the
+// layout mdata import reads.
+const (
+	FormatTable = "table"
+	FormatJson  = "json"
+	FormatYaml  = "yaml"
+	FormatCsv   = "csv"
+)
+
+// WriteProducts writes products in format, JSON and YAML as a list
+func WriteProducts(out io.Writer, format string, products []*model.Product) error {
+	if products == nil {
+		// An empty list rather than null
+		products = []*model.Product{}
+	}
+	switch format {
+	case FormatTable:
+		return writeTable(out, products)
+	case FormatJson:
+		return writeJson(out, products)
+	case FormatYaml:
+		return writeYaml(out, products)
+	case FormatCsv:
+		return writeCsv(out, products)
+	}
+	return fmt.Errorf("Unknown output format: %v", format)
+}
+
+// WriteProduct writes a single product in format, JSON and YAML as an object
+func WriteProduct(out io.Writer, format string, product *model.Product) error {
+	switch format {
+	case FormatJson:
+		return writeJson(out, product)
+	case FormatYaml:
+		return writeYaml(out, product)
+	}
+	return WriteProducts(out, format, []*model.Product{product})
+}
+
+func writeTable(out io.Writer, products []*model.Product) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "GTIN\tSTATE\tVERSION\tATTRIBUTES")
+	for _, product := range products {
+		var attrs []string
+		for _, key := range attributeKeys([]*model.Product{product}) {
+			attrs = append(attrs, key+"="+product.Attributes[key])
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", product.Gtin, product.State, product.Version, strings.Join(attrs, " "))
+	}
+	return w.Flush()
+}
+
+func writeJson(out io.Writer, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
+func writeYaml(out io.Writer, value interface{}) error {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+func writeCsv(out io.Writer, products []*model.Product) error {
+	w, err := NewCsvWriter(out, attributeKeys(products))
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		err = w.Write(product)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// CsvWriter writes products as CSV a row at a time, for exports too large to
+// hold in memory
+type CsvWriter struct {
+	w    *csv.Writer
+	keys []string
+}
+
+// NewCsvWriter writes the header of a CSV with a column for each attribute
+// key. mdata import trims column names and matches them ignoring case, so a
+// key it would read differently, naming the gtin or state column or differing
+// from another only in case, is refused rather than written to a file that
+// cannot be imported back.
+func NewCsvWriter(out io.Writer, keys []string) (*CsvWriter, error) {
+	columns := map[string]string{"gtin": "gtin", "state": "state"}
+	for _, key := range keys {
+		if key == "" || strings.TrimSpace(key) != key {
+			return nil, fmt.Errorf("Attribute '%v' cannot be written as CSV, import would trim its column name", key)
+		}
+		column, ok := columns[strings.ToLower(key)]
+		if ok {
+			return nil, fmt.Errorf("Attribute '%v' cannot be written as CSV, its column would clash with column '%v'", key, column)
+		}
+		columns[strings.ToLower(key)] = key
+	}
+
+	w := &CsvWriter{w: csv.NewWriter(out), keys: keys}
+	err := w.w.Write(append([]string{"gtin", "state"}, keys...))
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write writes the row of product, leaving the cells of attributes it does
+// not have empty. Attributes without a column are left out.
+func (self *CsvWriter) Write(product *model.Product) error {
+	record := []string{product.Gtin, product.State}
+	for _, key := range self.keys {
+		record = append(record, product.Attributes[key])
+	}
+	return self.w.Write(record)
+}
+
+// Flush writes any buffered rows to the underlying writer
+func (self *CsvWriter) Flush() error {
+	self.w.Flush()
+	return self.w.Error()
+}
+
+// attributeKeys returns the attribute keys of products, sorted
+func attributeKeys(products []*model.Product) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, product := range products {
+		for key := range product.Attributes {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
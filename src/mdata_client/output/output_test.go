@@ -0,0 +1,104 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+var testProducts = []*model.Product{
+	{Gtin: "00012345600012", State: "ACTIVE", Attributes: map[string]string{"uom": "cases", "brand": "Acme, Inc."},
+		Creator: "02aa", Owners: []string{"02aa"}, Version: 2},
+	{Gtin: "00012345600029", State: "INACTIVE", Attributes: map[string]string{"uom": "lbs"}},
+}
+
+func TestWriteProducts(t *testing.T) {
+
+	tests := map[string]struct {
+		format   string
+		products []*model.Product
+		out      string
+	}{
+		"table": {
+			format:   FormatTable,
+			products: testProducts,
+			out: "GTIN            STATE     VERSION  ATTRIBUTES\n" +
+				"00012345600012  ACTIVE    2        brand=Acme, Inc. uom=cases\n" +
+				"00012345600029  INACTIVE  0        uom=lbs\n",
+		},
+		"json": {
+			format:   FormatJson,
+			products: testProducts[1:],
+			out: "[\n  {\n    \"gtin\": \"00012345600029\",\n    \"state\": \"INACTIVE\",\n" +
+				"    \"attributes\": {\n      \"uom\": \"lbs\"\n    },\n    \"version\": 0\n  }\n]\n",
+		},
+		"jsonEmpty": {
+			format:   FormatJson,
+			products: nil,
+			out:      "[]\n",
+		},
+		"yaml": {
+			format:   FormatYaml,
+			products: testProducts[:1],
+			out: "- gtin: \"00012345600012\"\n  state: ACTIVE\n  attributes:\n    brand: Acme, Inc.\n    uom: cases\n" +
+				"  creator: 02aa\n  owners:\n  - 02aa\n  version: 2\n",
+		},
+		"csv": {
+			format:   FormatCsv,
+			products: testProducts,
+			out:      "gtin,state,brand,uom\n00012345600012,ACTIVE,\"Acme, Inc.\",cases\n00012345600029,INACTIVE,,lbs\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		out := &bytes.Buffer{}
+		err := WriteProducts(out, test.format, test.products)
+		assert.Nil(t, err)
+		assert.Equal(t, test.out, out.String())
+	}
+
+	assert.NotNil(t, WriteProducts(&bytes.Buffer{}, "xml", testProducts))
+}
+
+func TestWriteProduct(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := WriteProduct(out, FormatJson, testProducts[1])
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  \"gtin\": \"00012345600029\",\n  \"state\": \"INACTIVE\",\n"+
+		"  \"attributes\": {\n    \"uom\": \"lbs\"\n  },\n  \"version\": 0\n}\n", out.String())
+}
+
+func TestCsvRoundTrip(t *testing.T) {
+	products := []*model.Product{
+		{Gtin: "00012345600012", State: "ACTIVE", Attributes: map[string]string{
+			"brand": "Acme, Inc.", "desc": "two\nlines", "quote": "\"quoted\"", "pad": "  padded", "name": "Café"}},
+		{Gtin: "00012345600029", State: "INACTIVE", Attributes: map[string]string{"uom": "lbs"}},
+	}
+
+	out := &bytes.Buffer{}
+	assert.Nil(t, WriteProducts(out, FormatCsv, products))
+	rows, err := client.ReadImportFile(out)
+	assert.Nil(t, err)
+	if assert.Len(t, rows, len(products)) {
+		for i, product := range products {
+			assert.Equal(t, product.Gtin, rows[i].Gtin)
+			assert.Equal(t, product.State, rows[i].State)
+			assert.Equal(t, map[string]string(product.Attributes), rows[i].Attributes)
+		}
+	}
+}
+
+func TestCsvColumnClash(t *testing.T) {
+	for _, keys := range [][]string{{"GTIN"}, {"uom", "State"}, {"uom", "UOM"}, {" uom"}, {""}} {
+		_, err := NewCsvWriter(&bytes.Buffer{}, keys)
+		assert.NotNil(t, err, "%v", keys)
+	}
+
+	w, err := NewCsvWriter(&bytes.Buffer{}, []string{"gtin_prefix", "uom"})
+	assert.Nil(t, err)
+	assert.Nil(t, w.Flush())
+}
@@ -0,0 +1,80 @@
+package indexer
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// schema of the index. state holds the data of every indexed address, the
+// other tables are derived from it. state_undo records the data an address
+// held before each change of a block, so the block can be rolled back when
+// another fork becomes the chain.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS blocks (
+		block_num         INTEGER PRIMARY KEY,
+		block_id          TEXT NOT NULL UNIQUE,
+		previous_block_id TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS state (
+		address TEXT PRIMARY KEY,
+		data    BLOB NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS state_undo (
+		block_num INTEGER NOT NULL,
+		seq       INTEGER NOT NULL,
+		address   TEXT NOT NULL,
+		data      BLOB,
+		PRIMARY KEY (block_num, seq)
+	)`,
+	`CREATE TABLE IF NOT EXISTS products (
+		gtin    TEXT PRIMARY KEY,
+		address TEXT NOT NULL,
+		state   TEXT NOT NULL,
+		creator TEXT NOT NULL,
+		owners  TEXT NOT NULL,
+		version INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS products_address ON products (address)`,
+	`CREATE INDEX IF NOT EXISTS products_state ON products (state)`,
+	`CREATE TABLE IF NOT EXISTS attributes (
+		gtin  TEXT NOT NULL,
+		key   TEXT NOT NULL,
+		value TEXT NOT NULL,
+		PRIMARY KEY (gtin, key)
+	)`,
+	`CREATE INDEX IF NOT EXISTS attributes_key_value ON attributes (key, value)`,
+	`CREATE TABLE IF NOT EXISTS history (
+		gtin           TEXT NOT NULL,
+		version        INTEGER NOT NULL,
+		address        TEXT NOT NULL,
+		signer         TEXT NOT NULL,
+		action         TEXT NOT NULL,
+		timestamp      INTEGER NOT NULL,
+		previous_state TEXT NOT NULL,
+		state          TEXT NOT NULL,
+		changes        TEXT NOT NULL,
+		owners         TEXT NOT NULL,
+		PRIMARY KEY (gtin, version)
+	)`,
+	`CREATE INDEX IF NOT EXISTS history_address ON history (address)`,
+}
+
+// openDatabase opens the SQLite database at path, creating the tables that
+// do not exist yet. The write-ahead log lets readers query the index while a
+// block is written.
+func openDatabase(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	for _, statement := range schema {
+		_, err = db.Exec(statement)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("Error creating tables in %v: %v", path, err)
+		}
+	}
+	return db, nil
+}
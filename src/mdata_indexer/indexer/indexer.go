@@ -0,0 +1,374 @@
+// Package indexer keeps an SQLite read model of the mdata products, their
+// attributes and their history, following the changes each committed block
+// makes to mdata state.
+package indexer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/logging"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"github.com/tross-tyson/mdata_go/src/protobuf/history_pb2"
+)
+
+var logger *logging.Logger = logging.Get()
+
+// Block ids sent when subscribing, so the validator finds the newest indexed
+// block still on the chain
+const knownBlockIds = 20
+
+// ErrUnknownParent is returned for a block whose previous block was not
+// indexed: the chain switched to a fork starting before the indexed blocks
+// kept, or blocks were missed. Subscribing again with KnownBlockIds delivers
+// the blocks following the newest indexed block still on the chain.
+var ErrUnknownParent = errors.New("Previous block is not indexed")
+
+// Indexer writes the changes of committed blocks to the index
+type Indexer struct {
+	db *sql.DB
+	// Blocks whose changes can be rolled back
+	keepBlocks uint64
+}
+
+// Open opens the index in the SQLite database at path. The changes of the
+// last keepBlocks blocks are kept to roll back forks; with 0 no block can be
+// rolled back.
+func Open(path string, keepBlocks uint64) (*Indexer, error) {
+	db, err := openDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Indexer{db: db, keepBlocks: keepBlocks}, nil
+}
+
+func (indexer *Indexer) Close() error {
+	return indexer.db.Close()
+}
+
+// Run indexes the blocks committed on the validator at url, starting after
+// the newest indexed block, until the connection fails
+func (indexer *Indexer) Run(url string) error {
+	for {
+		known, err := indexer.KnownBlockIds(knownBlockIds)
+		if err != nil {
+			return err
+		}
+		stream, err := client.SubscribeStateDeltas(url, known)
+		if err != nil {
+			return err
+		}
+		err = indexer.follow(stream)
+		if err != ErrUnknownParent {
+			return err
+		}
+		logger.Info("Chain switched to a fork before the indexed blocks, subscribing again")
+		stream.Close()
+	}
+}
+
+func (indexer *Indexer) follow(stream *client.EventStream) error {
+	for {
+		block, err := stream.Next()
+		if err != nil {
+			return err
+		}
+		err = indexer.ApplyBlock(block)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// KnownBlockIds returns the ids of up to n of the newest indexed blocks,
+// newest first, followed by client.NullBlockId so an empty index or one left
+// behind by a fork is rebuilt from the genesis block
+func (indexer *Indexer) KnownBlockIds(n int) ([]string, error) {
+	rows, err := indexer.db.Query("SELECT block_id FROM blocks ORDER BY block_num DESC LIMIT ?", n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return append(ids, client.NullBlockId), nil
+}
+
+// ApplyBlock indexes the changes of block in one transaction. Indexed blocks
+// following block's previous block are rolled back first, as the chain
+// switched to the fork block is on.
+func (indexer *Indexer) ApplyBlock(block *client.BlockEvents) (err error) {
+	tx, err := indexer.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Blocks may be delivered again after subscribing again
+	var indexed int
+	err = tx.QueryRow("SELECT COUNT(*) FROM blocks WHERE block_id = ?", block.BlockId).Scan(&indexed)
+	if err != nil {
+		return err
+	}
+	if indexed > 0 {
+		return tx.Commit()
+	}
+
+	if block.PreviousBlockId == client.NullBlockId {
+		err = clearIndex(tx)
+	} else {
+		var parentNum int64
+		err = tx.QueryRow("SELECT block_num FROM blocks WHERE block_id = ?", block.PreviousBlockId).Scan(&parentNum)
+		if err == sql.ErrNoRows {
+			return ErrUnknownParent
+		}
+		if err == nil {
+			err = rollback(tx, parentNum)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO blocks (block_num, block_id, previous_block_id) VALUES (?, ?, ?)",
+		block.BlockNum, block.BlockId, block.PreviousBlockId)
+	if err != nil {
+		return err
+	}
+	for seq, change := range block.StateChanges {
+		// Only products and their history are indexed
+		if model.IsReserved(change.Address) && !model.IsHistoryAddress(change.Address) {
+			continue
+		}
+		err = applyChange(tx, block.BlockNum, seq, change)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The oldest block kept is the parent of the oldest block that can be
+	// rolled back, so its own changes are no longer needed
+	if block.BlockNum >= indexer.keepBlocks {
+		oldest := block.BlockNum - indexer.keepBlocks
+		_, err = tx.Exec("DELETE FROM state_undo WHERE block_num <= ?", oldest)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM blocks WHERE block_num < ?", oldest)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	logger.Debugf("Indexed block %v %v with %v state changes", block.BlockNum, block.BlockId, len(block.StateChanges))
+	return nil
+}
+
+// applyChange stores a change to an address, recording the data it replaces
+func applyChange(tx *sql.Tx, blockNum uint64, seq int, change *client.StateChange) error {
+	var previous []byte
+	err := tx.QueryRow("SELECT data FROM state WHERE address = ?", change.Address).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO state_undo (block_num, seq, address, data) VALUES (?, ?, ?, ?)",
+		blockNum, seq, change.Address, previous)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if !change.Deleted {
+		data = change.Value
+	}
+	return setState(tx, change.Address, data)
+}
+
+// rollback undoes the changes of the blocks after parentNum, newest first
+func rollback(tx *sql.Tx, parentNum int64) error {
+	rows, err := tx.Query("SELECT address, data FROM state_undo WHERE block_num > ? ORDER BY block_num DESC, seq DESC", parentNum)
+	if err != nil {
+		return err
+	}
+	type undo struct {
+		address string
+		data    []byte
+	}
+	var undos []undo
+	for rows.Next() {
+		var u undo
+		err = rows.Scan(&u.address, &u.data)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		undos = append(undos, u)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, u := range undos {
+		err = setState(tx, u.address, u.data)
+		if err != nil {
+			return err
+		}
+	}
+	if len(undos) > 0 {
+		logger.Infof("Rolled back %v state changes after block %v", len(undos), parentNum)
+	}
+	_, err = tx.Exec("DELETE FROM state_undo WHERE block_num > ?", parentNum)
+	if err == nil {
+		_, err = tx.Exec("DELETE FROM blocks WHERE block_num > ?", parentNum)
+	}
+	return err
+}
+
+// clearIndex empties the index before it is rebuilt from the genesis block
+func clearIndex(tx *sql.Tx) error {
+	for _, table := range []string{"blocks", "state", "state_undo", "products", "attributes", "history"} {
+		_, err := tx.Exec("DELETE FROM " + table)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setState stores the data of an address, or deletes it when data is nil,
+// and indexes the records it holds again
+func setState(tx *sql.Tx, address string, data []byte) error {
+	var err error
+	if data == nil {
+		_, err = tx.Exec("DELETE FROM state WHERE address = ?", address)
+	} else {
+		_, err = tx.Exec("INSERT OR REPLACE INTO state (address, data) VALUES (?, ?)", address, data)
+	}
+	if err != nil {
+		return err
+	}
+	if model.IsHistoryAddress(address) {
+		return indexHistory(tx, address, data)
+	}
+	return indexProducts(tx, address, data)
+}
+
+// indexProducts replaces the products indexed for address with those
+// stored in data
+func indexProducts(tx *sql.Tx, address string, data []byte) error {
+	_, err := tx.Exec("DELETE FROM attributes WHERE gtin IN (SELECT gtin FROM products WHERE address = ?)", address)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM products WHERE address = ?", address)
+	if err != nil || data == nil {
+		return err
+	}
+
+	products, err := model.DecodeProducts(data)
+	if err != nil {
+		return fmt.Errorf("Address %v: %v", address, err)
+	}
+	for _, product := range products {
+		owners, err := encodeOwners(product.Owners)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO products (gtin, address, state, creator, owners, version) VALUES (?, ?, ?, ?, ?, ?)",
+			product.Gtin, address, product.State, product.Creator, string(owners), product.Version)
+		if err != nil {
+			return err
+		}
+		for key, value := range product.Attributes {
+			_, err = tx.Exec("INSERT INTO attributes (gtin, key, value) VALUES (?, ?, ?)", product.Gtin, key, value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeOwners stores owners as a JSON array, empty when there are none
+func encodeOwners(owners []string) ([]byte, error) {
+	if owners == nil {
+		owners = []string{}
+	}
+	return json.Marshal(owners)
+}
+
+// AttributeChange is an attribute change of a history entry, stored as JSON
+type AttributeChange struct {
+	Kind     string `json:"kind"`
+	Key      string `json:"key"`
+	OldValue string `json:"old_value,omitempty"`
+	NewValue string `json:"new_value,omitempty"`
+}
+
+// indexHistory replaces the history entries indexed for address with those
+// stored in data
+func indexHistory(tx *sql.Tx, address string, data []byte) error {
+	_, err := tx.Exec("DELETE FROM history WHERE address = ?", address)
+	if err != nil || data == nil {
+		return err
+	}
+
+	container := &history_pb2.HistoryContainer{}
+	err = proto.Unmarshal(data, container)
+	if err != nil {
+		return fmt.Errorf("Address %v: malformed history data: %v", address, err)
+	}
+	for _, history := range container.GetEntries() {
+		for _, entry := range history.GetEntries() {
+			changes := []AttributeChange{}
+			for _, change := range entry.GetChanges() {
+				changes = append(changes, AttributeChange{
+					Kind:     strings.ToLower(change.GetKind().String()),
+					Key:      change.GetKey(),
+					OldValue: change.GetOldValue(),
+					NewValue: change.GetNewValue(),
+				})
+			}
+			changesJson, err := json.Marshal(changes)
+			if err != nil {
+				return err
+			}
+			owners, err := encodeOwners(entry.GetOwners())
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`INSERT INTO history (gtin, version, address, signer, action, timestamp, previous_state, state, changes, owners)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				history.GetGtin(), entry.GetVersion(), address, entry.GetSigner(), entry.GetAction(), entry.GetTimestamp(),
+				entry.GetPreviousState(), entry.GetState(), string(changesJson), string(owners))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package indexer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"github.com/tross-tyson/mdata_go/src/protobuf/history_pb2"
+)
+
+const (
+	gtin1 = "00012345600012"
+	gtin2 = "00012345600029"
+)
+
+func tempIndexer(t *testing.T) (*Indexer, func()) {
+	dir, err := ioutil.TempDir("", "mdata_indexer")
+	assert.Nil(t, err)
+	indexer, err := Open(filepath.Join(dir, "mdata.db"), 2)
+	assert.Nil(t, err)
+	return indexer, func() {
+		indexer.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func productChange(t *testing.T, product *model.Product) *client.StateChange {
+	data, err := model.EncodeProducts([]*model.Product{product})
+	assert.Nil(t, err)
+	return &client.StateChange{Address: model.ProductAddress(product.Gtin), Value: data}
+}
+
+func historyChange(t *testing.T, gtin string, entries ...*history_pb2.HistoryEntry) *client.StateChange {
+	data, err := proto.Marshal(&history_pb2.HistoryContainer{
+		Entries: []*history_pb2.ProductHistory{{Gtin: gtin, Entries: entries}},
+	})
+	assert.Nil(t, err)
+	return &client.StateChange{Address: model.HistoryAddress(gtin, 1), Value: data}
+}
+
+func block(num uint64, id string, previous string, changes ...*client.StateChange) *client.BlockEvents {
+	return &client.BlockEvents{BlockId: id, BlockNum: num, PreviousBlockId: previous, StateChanges: changes}
+}
+
+func product(gtin string, state string, version uint64, attributes model.Attributes) *model.Product {
+	return &model.Product{Gtin: gtin, State: state, Creator: "02aa", Owners: []string{"02aa"}, Version: version,
+		Attributes: attributes}
+}
+
+// indexed returns the state, version and attributes of the indexed products
+func indexed(t *testing.T, indexer *Indexer) map[string]*model.Product {
+	products := map[string]*model.Product{}
+	rows, err := indexer.db.Query("SELECT gtin, state, version FROM products")
+	assert.Nil(t, err)
+	for rows.Next() {
+		p := &model.Product{Attributes: model.Attributes{}}
+		assert.Nil(t, rows.Scan(&p.Gtin, &p.State, &p.Version))
+		products[p.Gtin] = p
+	}
+	rows.Close()
+
+	rows, err = indexer.db.Query("SELECT gtin, key, value FROM attributes")
+	assert.Nil(t, err)
+	for rows.Next() {
+		var gtin, key, value string
+		assert.Nil(t, rows.Scan(&gtin, &key, &value))
+		products[gtin].Attributes[key] = value
+	}
+	rows.Close()
+	return products
+}
+
+func TestApplyBlock(t *testing.T) {
+	indexer, cleanup := tempIndexer(t)
+	defer cleanup()
+
+	assert.Nil(t, indexer.ApplyBlock(block(0, "genesis", client.NullBlockId,
+		// Organizations are not indexed
+		&client.StateChange{Address: model.OrganizationAddress("org"), Value: []byte("org")},
+		productChange(t, product(gtin1, "ACTIVE", 1, model.Attributes{"uom": "cases"})),
+		historyChange(t, gtin1, &history_pb2.HistoryEntry{Version: 1, Signer: "02aa", Action: "create", Timestamp: 10,
+			State: "ACTIVE", Owners: []string{"02aa"}, Changes: []*history_pb2.AttributeChange{
+				{Kind: history_pb2.AttributeChange_ADDED, Key: "uom", NewValue: "cases"},
+			}}),
+	)))
+	assert.Nil(t, indexer.ApplyBlock(block(1, "b1", "genesis",
+		productChange(t, product(gtin1, "INACTIVE", 2, model.Attributes{"uom": "each"})),
+		productChange(t, product(gtin2, "ACTIVE", 1, nil)),
+	)))
+
+	assert.Equal(t, map[string]*model.Product{
+		gtin1: {Gtin: gtin1, State: "INACTIVE", Version: 2, Attributes: model.Attributes{"uom": "each"}},
+		gtin2: {Gtin: gtin2, State: "ACTIVE", Version: 1, Attributes: model.Attributes{}},
+	}, indexed(t, indexer))
+
+	var action, changes, owners string
+	err := indexer.db.QueryRow("SELECT action, changes, owners FROM history WHERE gtin = ? AND version = 1", gtin1).
+		Scan(&action, &changes, &owners)
+	assert.Nil(t, err)
+	assert.Equal(t, "create", action)
+	assert.Equal(t, `[{"kind":"added","key":"uom","new_value":"cases"}]`, changes)
+	assert.Equal(t, `["02aa"]`, owners)
+
+	var organizations int
+	assert.Nil(t, indexer.db.QueryRow("SELECT COUNT(*) FROM state WHERE address = ?",
+		model.OrganizationAddress("org")).Scan(&organizations))
+	assert.Equal(t, 0, organizations)
+
+	// Blocks already indexed are skipped
+	assert.Nil(t, indexer.ApplyBlock(block(1, "b1", "genesis",
+		&client.StateChange{Address: model.ProductAddress(gtin2), Deleted: true},
+	)))
+	assert.Len(t, indexed(t, indexer), 2)
+
+	// Only the last blocks and the block they follow are kept to roll back
+	assert.Nil(t, indexer.ApplyBlock(block(2, "b2", "b1")))
+	assert.Nil(t, indexer.ApplyBlock(block(3, "b3", "b2")))
+	known, err := indexer.KnownBlockIds(10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b3", "b2", "b1", client.NullBlockId}, known)
+	var undo int
+	assert.Nil(t, indexer.db.QueryRow("SELECT COUNT(*) FROM state_undo WHERE block_num <= 1").Scan(&undo))
+	assert.Equal(t, 0, undo)
+}
+
+func TestApplyBlockKeepNone(t *testing.T) {
+	indexer, cleanup := tempIndexer(t)
+	defer cleanup()
+	indexer.keepBlocks = 0
+
+	// The newest block is kept, so the next block follows it
+	assert.Nil(t, indexer.ApplyBlock(block(0, "genesis", client.NullBlockId)))
+	assert.Nil(t, indexer.ApplyBlock(block(1, "b1", "genesis",
+		productChange(t, product(gtin1, "ACTIVE", 1, nil)),
+	)))
+	assert.Nil(t, indexer.ApplyBlock(block(2, "b2", "b1",
+		productChange(t, product(gtin1, "INACTIVE", 2, nil)),
+	)))
+	assert.Len(t, indexed(t, indexer), 1)
+	known, err := indexer.KnownBlockIds(10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b2", client.NullBlockId}, known)
+}
+
+func TestApplyBlockFork(t *testing.T) {
+	testCases := map[string]struct {
+		fork     []*client.BlockEvents
+		expected map[string]*model.Product
+		known    []string
+	}{
+		"rollback": {
+			fork: []*client.BlockEvents{
+				block(2, "c2", "b1", productChange(t, product(gtin1, "DISCONTINUED", 2, nil))),
+			},
+			expected: map[string]*model.Product{
+				gtin1: {Gtin: gtin1, State: "DISCONTINUED", Version: 2, Attributes: model.Attributes{}},
+			},
+			known: []string{"c2", "b1", "genesis", client.NullBlockId},
+		},
+		"rollbackAll": {
+			fork: []*client.BlockEvents{
+				block(1, "c1", "genesis"),
+			},
+			expected: map[string]*model.Product{},
+			known:    []string{"c1", "genesis", client.NullBlockId},
+		},
+		"replay": {
+			fork: []*client.BlockEvents{
+				block(0, "other", client.NullBlockId, productChange(t, product(gtin2, "ACTIVE", 1, nil))),
+			},
+			expected: map[string]*model.Product{
+				gtin2: {Gtin: gtin2, State: "ACTIVE", Version: 1, Attributes: model.Attributes{}},
+			},
+			known: []string{"other", client.NullBlockId},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Logf("Running test case: %s", name)
+		indexer, cleanup := tempIndexer(t)
+		indexer.keepBlocks = 10
+		assert.Nil(t, indexer.ApplyBlock(block(0, "genesis", client.NullBlockId)))
+		assert.Nil(t, indexer.ApplyBlock(block(1, "b1", "genesis",
+			productChange(t, product(gtin1, "ACTIVE", 1, model.Attributes{"uom": "cases"})),
+		)))
+		assert.Nil(t, indexer.ApplyBlock(block(2, "b2", "b1",
+			productChange(t, product(gtin1, "INACTIVE", 2, nil)),
+			productChange(t, product(gtin2, "ACTIVE", 1, nil)),
+		)))
+		assert.Nil(t, indexer.ApplyBlock(block(3, "b3", "b2",
+			&client.StateChange{Address: model.ProductAddress(gtin2), Deleted: true},
+		)))
+
+		for _, forkBlock := range test.fork {
+			assert.Nil(t, indexer.ApplyBlock(forkBlock))
+		}
+		assert.Equal(t, test.expected, indexed(t, indexer))
+		known, err := indexer.KnownBlockIds(10)
+		assert.Nil(t, err)
+		assert.Equal(t, test.known, known)
+		cleanup()
+	}
+}
+
+func TestApplyBlockUnknownParent(t *testing.T) {
+	indexer, cleanup := tempIndexer(t)
+	defer cleanup()
+
+	assert.Nil(t, indexer.ApplyBlock(block(0, "genesis", client.NullBlockId,
+		productChange(t, product(gtin1, "ACTIVE", 1, nil)),
+	)))
+	assert.Equal(t, ErrUnknownParent, indexer.ApplyBlock(block(5, "c5", "c4",
+		productChange(t, product(gtin2, "ACTIVE", 1, nil)),
+	)))
+	// The index is unchanged
+	assert.Len(t, indexed(t, indexer), 1)
+	known, err := indexer.KnownBlockIds(10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"genesis", client.NullBlockId}, known)
+}
@@ -0,0 +1,170 @@
+package indexer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+// Catalogue queries the products and history in the index
+type Catalogue struct {
+	db *sql.DB
+}
+
+// HistoryEntry is a change recorded in the history of a product
+type HistoryEntry struct {
+	Version       uint64            `json:"version"`
+	Signer        string            `json:"signer"`
+	Action        string            `json:"action"`
+	Timestamp     int64             `json:"timestamp"`
+	PreviousState string            `json:"previous_state"`
+	State         string            `json:"state"`
+	Changes       []AttributeChange `json:"changes"`
+	// Owners set by the change, empty when they did not change
+	Owners []string `json:"owners"`
+}
+
+// OpenCatalogue opens the index in the SQLite database at path for queries,
+// while an Indexer writes to it
+func OpenCatalogue(path string) (*Catalogue, error) {
+	db, err := openDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Catalogue{db: db}, nil
+}
+
+func (catalogue *Catalogue) Close() error {
+	return catalogue.db.Close()
+}
+
+// Products returns the products passing filter sorted by GTIN, skipping the
+// first offset of them and returning at most limit
+func (catalogue *Catalogue) Products(filter *client.ProductFilter, offset int, limit int) ([]*model.Product, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.GtinPrefix != "" {
+		conditions = append(conditions, "substr(gtin, 1, ?) = ?")
+		args = append(args, len(filter.GtinPrefix), filter.GtinPrefix)
+	}
+	if len(filter.States) > 0 {
+		conditions = append(conditions, "state IN (?"+strings.Repeat(", ?", len(filter.States)-1)+")")
+		for _, state := range filter.States {
+			args = append(args, state)
+		}
+	}
+	for key, value := range filter.Attributes {
+		conditions = append(conditions, "gtin IN (SELECT gtin FROM attributes WHERE key = ? AND value = ?)")
+		args = append(args, key, value)
+	}
+	for _, key := range filter.AttributesExist {
+		conditions = append(conditions, "gtin IN (SELECT gtin FROM attributes WHERE key = ?)")
+		args = append(args, key)
+	}
+
+	query := "SELECT gtin, state, creator, owners, version FROM products"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY gtin LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+	return catalogue.products(query, args...)
+}
+
+// Product returns the product with the GTIN-14 gtin, or nil if there is none
+func (catalogue *Catalogue) Product(gtin string) (*model.Product, error) {
+	products, err := catalogue.products("SELECT gtin, state, creator, owners, version FROM products WHERE gtin = ?", gtin)
+	if err != nil || len(products) == 0 {
+		return nil, err
+	}
+	return products[0], nil
+}
+
+// History returns the history of the product with the GTIN-14 gtin, oldest
+// first. The history of a deleted product is kept.
+func (catalogue *Catalogue) History(gtin string) ([]*HistoryEntry, error) {
+	rows, err := catalogue.db.Query(`SELECT version, signer, action, timestamp, previous_state, state, changes, owners
+		FROM history WHERE gtin = ? ORDER BY version`, gtin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*HistoryEntry{}
+	for rows.Next() {
+		entry := &HistoryEntry{}
+		var changes, owners string
+		err = rows.Scan(&entry.Version, &entry.Signer, &entry.Action, &entry.Timestamp, &entry.PreviousState,
+			&entry.State, &changes, &owners)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal([]byte(changes), &entry.Changes)
+		if err == nil {
+			err = json.Unmarshal([]byte(owners), &entry.Owners)
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// products runs a query selecting products and reads their attributes
+func (catalogue *Catalogue) products(query string, args ...interface{}) ([]*model.Product, error) {
+	tx, err := catalogue.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	// Products and attributes are read from the same snapshot
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	products := []*model.Product{}
+	byGtin := map[string]*model.Product{}
+	for rows.Next() {
+		product := &model.Product{Attributes: model.Attributes{}}
+		var owners string
+		err = rows.Scan(&product.Gtin, &product.State, &product.Creator, &owners, &product.Version)
+		if err == nil {
+			err = json.Unmarshal([]byte(owners), &product.Owners)
+		}
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		products = append(products, product)
+		byGtin[product.Gtin] = product
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil || len(products) == 0 {
+		return products, err
+	}
+
+	gtins := make([]interface{}, 0, len(products))
+	for _, product := range products {
+		gtins = append(gtins, product.Gtin)
+	}
+	rows, err = tx.Query("SELECT gtin, key, value FROM attributes WHERE gtin IN (?"+
+		strings.Repeat(", ?", len(gtins)-1)+")", gtins...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var gtin, key, value string
+		err = rows.Scan(&gtin, &key, &value)
+		if err != nil {
+			return nil, err
+		}
+		byGtin[gtin].Attributes[key] = value
+	}
+	return products, rows.Err()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hyperledger/sawtooth-sdk-go/logging"
+	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/mdata_indexer/indexer"
+	"os"
+)
+
+type Opts struct {
+	Verbose    []bool `short:"v" long:"verbose" description:"Increase verbosity"`
+	Connect    string `short:"C" long:"connect" description:"Validator component endpoint to connect to" default:"tcp://localhost:4004"`
+	Database   string `long:"database" description:"SQLite database holding the index" default:"/var/lib/mdata_indexer/mdata.db"`
+	KeepBlocks uint64 `long:"keep-blocks" description:"Number of blocks that can be rolled back when the chain switches forks" default:"1000"`
+}
+
+func main() {
+	var opts Opts
+
+	logger := logging.Get()
+
+	parser := flags.NewParser(&opts, flags.Default)
+	remaining, err := parser.Parse()
+	if err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		} else {
+			logger.Errorf("Failed to parse args: %v", err)
+			os.Exit(2)
+		}
+	}
+
+	if len(remaining) > 0 {
+		fmt.Printf("Error: Unrecognized arguments passed: %v\n", remaining)
+		os.Exit(2)
+	}
+
+	switch len(opts.Verbose) {
+	case 2:
+		logger.SetLevel(logging.DEBUG)
+	case 1:
+		logger.SetLevel(logging.INFO)
+	default:
+		logger.SetLevel(logging.WARN)
+	}
+
+	index, err := indexer.Open(opts.Database, opts.KeepBlocks)
+	if err != nil {
+		logger.Errorf("Failed to open %v: %v", opts.Database, err)
+		os.Exit(1)
+	}
+	defer index.Close()
+	logger.Infof("Indexing blocks from %v into %v", opts.Connect, opts.Database)
+
+	err = index.Run(opts.Connect)
+	if err != nil {
+		logger.Error("Indexer stopped: ", err)
+		os.Exit(1)
+	}
+}
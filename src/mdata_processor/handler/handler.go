@@ -19,9 +19,11 @@ package handler
 
 import (
 	"fmt"
+	"strings"
+	"github.com/tross-tyson/mdata_go/src/lifecycle"
 	"github.com/tross-tyson/mdata_go/src/mdata_processor/mdata_payload"
 	"github.com/tross-tyson/mdata_go/src/mdata_processor/mdata_state"
-	"strings"
+	"github.com/tross-tyson/mdata_go/src/model"
 
 	"github.com/hyperledger/sawtooth-sdk-go/logging"
 	"github.com/hyperledger/sawtooth-sdk-go/processor"
@@ -30,6 +32,13 @@ import (
 
 var logger *logging.Logger = logging.Get()
 
+const (
+	// Comma separated payload: "action,gtin,key=value,...,state"
+	familyVersionCsv = "1.0"
+	// Protobuf encoded MdPayload, see protos/mdata_payload.proto
+	familyVersionProtobuf = "2.0"
+)
+
 type MdHandler struct {
 }
 
@@ -41,7 +50,7 @@ func (self *MdHandler) FamilyName() string {
 
 func (self *MdHandler) FamilyVersions() []string {
 	// Versions allow you to correlate deployments among all the nodes in your  network. You want all the nodes using the same version
-	return []string{"1.0"}
+	return []string{familyVersionCsv, familyVersionProtobuf}
 }
 
 func (self *MdHandler) Namespaces() []string {
@@ -49,7 +58,7 @@ func (self *MdHandler) Namespaces() []string {
 	All data under a namespace prefix follows a consistent address and data encoding/serialization schem that is determined
 	by the transaction family which defines the namespace
 	*/
-	return []string{mdata_state.Namespace}
+	return []string{model.Namespace}
 }
 
 //Signature before interfaces:
@@ -70,6 +79,16 @@ func (self *MdHandler) Apply(request *processor_pb2.TpProcessRequest, context *p
 	// 	contextId  string
 	// }
 
+	// Context provides an abstract interface for getting and setting validator
+	// state. All validator interactions by a handler should be through a Context
+	// instance. Currently, the Context class is NOT thread-safe and Context classes
+	// may not share the same messaging.Connection object.
+	return self.apply(request, mdata_state.NewMdState(context))
+}
+
+// apply applies the transaction in request to the state read and written by
+// mdState
+func (self *MdHandler) apply(request *processor_pb2.TpProcessRequest, mdState *mdata_state.MdState) error {
 	// The master data organization is defined as the signer of the transaction, so we unpack
 	// the transaction header to obtain the signer's public key, which will be
 	// used as the organization's identity.
@@ -79,66 +98,246 @@ func (self *MdHandler) Apply(request *processor_pb2.TpProcessRequest, context *p
 	// The payload is sent to the transaction processor as bytes (just as it
 	// appears in the transaction constructed by the transactor).  We unpack
 	// the payload into an MdPayload struct so we can access its fields.
-	payload, err := mdata_payload.FromBytes(request.GetPayload())
+	// The family version in the header tells us how the bytes are encoded.
+	var payload *mdata_payload.MdPayload
+	var err error
+	// Family version 1.0 predates product owners and organizations, so its
+	// transactions create products without owners and change them unchecked
+	legacy := false
+	switch header.GetFamilyVersion() {
+	case familyVersionCsv:
+		payload, err = mdata_payload.FromBytes(request.GetPayload())
+		legacy = true
+	default:
+		payload, err = mdata_payload.FromProtobuf(request.GetPayload())
+	}
 	if err != nil {
 		return err
 	}
 
-	// Context provides an abstract interface for getting and setting validator
-	// state. All validator interactions by a handler should be through a Context
-	// instance. Currently, the Context class is NOT thread-safe and Context classes
-	// may not share the same messaging.Connection object.
-	mdState := mdata_state.NewMdState(context)
-
-	logger.Debugf("mdata txn %v: signer %v: payload: Action='%v', Gtin='%v', Attributes='%v'",
-		request.GetSignature(), signer, payload.Action, payload.Gtin, payload.Attributes)
+	logger.Debugf("mdata txn %v: signer %v: payload: Action='%v', Gtin='%v', Attributes='%v', RemoveKeys='%v', Timestamp='%v', OrgId='%v', NewOwner='%v', PublicKey='%v', Roles='%v'",
+		request.GetSignature(), signer, payload.Action, payload.Gtin, payload.Attributes, payload.RemoveKeys, payload.Timestamp, payload.OrgId, payload.NewOwner, payload.PublicKey, payload.Roles)
 
 	switch payload.Action {
 	case "create":
-		err := validateCreate(mdState, payload.Gtin)
+		err := validateCreate(mdState, payload.Gtin, signer, legacy, header.GetInputs())
 		if err != nil {
 			return err
 		}
-		product := &mdata_state.Product{
+		productLifecycle, err := getLifecycle(mdState, legacy)
+		if err != nil {
+			return err
+		}
+		product := &model.Product{
 			Gtin:       payload.Gtin,
-			Attributes: mdata_state.DeserializeAttributes(payload.Attributes),
-			State:      "ACTIVE",
+			Attributes: model.DeserializeAttributes(payload.Attributes),
+			State:      productLifecycle.InitialState,
+			Creator:    signer,
+		}
+		if !legacy {
+			// The product belongs to the organization owning its company
+			// prefix: its admins own it, and agents only act on it through
+			// the roles they hold
+			organization, err := mdState.GetGtinOwner(payload.Gtin)
+			if err != nil {
+				return err
+			}
+			product.Owners = organization.Admins
 		}
-		displayCreate(payload, signer)
-		return mdState.SetProduct(payload.Gtin, product)
+		return storeProduct(mdState, legacy, payload, signer, product)
 	case "delete":
-		err := validateDelete(mdState, payload.Gtin)
+		err := validateDelete(mdState, payload.Gtin, signer, legacy)
 		if err != nil {
 			return err
 		}
-		displayDelete(signer, payload.Gtin)
-		return mdState.DeleteProduct(payload.Gtin)
+		return storeProduct(mdState, legacy, payload, signer, nil)
 	case "update":
-		err := validateUpdate(mdState, payload.Gtin)
+		err := validateUpdate(mdState, payload.Gtin, signer, legacy, payload.CheckVersion, payload.ExpectedVersion)
 		if err != nil {
 			return err
 		}
 		product, _ := mdState.GetProduct(payload.Gtin) //err is not needed here, as it is checked in the validateUpdate function
-		product.Attributes = mdata_state.DeserializeAttributes(payload.Attributes)
-		product.State = "ACTIVE"
-		displayUpdate(payload, signer, product)
-		return mdState.SetProduct(payload.Gtin, product)
+		productLifecycle, _ := getLifecycle(mdState, legacy) //err is not needed here, as it is checked in the validateUpdate function
+		product.Attributes = model.DeserializeAttributes(payload.Attributes)
+		product.State = productLifecycle.InitialState
+		return storeProduct(mdState, legacy, payload, signer, product)
+	case "patch":
+		err := validatePatch(mdState, payload.Gtin, signer, legacy)
+		if err != nil {
+			return err
+		}
+		product, _ := mdState.GetProduct(payload.Gtin) //err is not needed here, as it is checked in the validatePatch function
+		for k, v := range model.DeserializeAttributes(payload.Attributes) {
+			product.Attributes[k] = v
+		}
+		for _, k := range payload.RemoveKeys {
+			delete(product.Attributes, k)
+		}
+		return storeProduct(mdState, legacy, payload, signer, product)
+	case "unset":
+		err := validateUnset(mdState, payload.Gtin, signer, legacy, payload.RemoveKeys)
+		if err != nil {
+			return err
+		}
+		product, _ := mdState.GetProduct(payload.Gtin) //err is not needed here, as it is checked in the validateUnset function
+		for _, k := range payload.RemoveKeys {
+			delete(product.Attributes, k)
+		}
+		return storeProduct(mdState, legacy, payload, signer, product)
 	case "set":
-		err := validateStateChange(mdState, payload.Gtin, payload.State)
+		err := validateStateChange(mdState, payload.Gtin, signer, legacy, payload.State)
 		if err != nil {
 			return err
 		}
 		product, _ := mdState.GetProduct(payload.Gtin) //err is not needed here, as it is checked in the validateDeactivate function
 		product.State = payload.State
-		displayStateChange(payload, signer, product)
-		return mdState.SetProduct(payload.Gtin, product)
+		return storeProduct(mdState, legacy, payload, signer, product)
+	case "transfer":
+		err := validateTransfer(mdState, payload.Gtin, signer, legacy)
+		if err != nil {
+			return err
+		}
+		product, _ := mdState.GetProduct(payload.Gtin) //err is not needed here, as it is checked in the validateTransfer function
+		product.Owners = []string{payload.NewOwner}
+		return storeProduct(mdState, legacy, payload, signer, product)
+	case "agent_create":
+		err := validateAgentCreate(mdState, payload, signer)
+		if err != nil {
+			return err
+		}
+		agent := &mdata_state.Agent{
+			PublicKey: payload.PublicKey,
+			OrgId:     payload.OrgId,
+			Roles:     payload.Roles,
+		}
+		err = mdState.SetAgent(agent.PublicKey, agent)
+		if err != nil {
+			return err
+		}
+		return mdState.AddAgentEvent(model.EventAgentCreated, signer, agent)
+	case "agent_update":
+		err := validateAgentUpdate(mdState, payload, signer)
+		if err != nil {
+			return err
+		}
+		agent, _ := mdState.GetAgent(payload.PublicKey) //err is not needed here, as it is checked in the validateAgentUpdate function
+		agent.Roles = payload.Roles
+		err = mdState.SetAgent(agent.PublicKey, agent)
+		if err != nil {
+			return err
+		}
+		return mdState.AddAgentEvent(model.EventAgentUpdated, signer, agent)
+	case "org_create":
+		err := validateOrgCreate(mdState, payload, signer)
+		if err != nil {
+			return err
+		}
+		admins := payload.Admins
+		if len(admins) == 0 {
+			admins = []string{signer}
+		}
+		organization := &mdata_state.Organization{
+			OrgId:           payload.OrgId,
+			Name:            payload.OrgName,
+			Admins:          admins,
+			CompanyPrefixes: payload.CompanyPrefixes,
+		}
+		err = storeOrganization(mdState, nil, organization)
+		if err != nil {
+			return err
+		}
+		return mdState.AddOrganizationEvent(model.EventOrgCreated, signer, organization)
+	case "org_update":
+		err := validateOrgUpdate(mdState, payload, signer)
+		if err != nil {
+			return err
+		}
+		previous, _ := mdState.GetOrganization(payload.OrgId) //err is not needed here, as it is checked in the validateOrgUpdate function
+		organization := &mdata_state.Organization{
+			OrgId:           payload.OrgId,
+			Name:            payload.OrgName,
+			Admins:          payload.Admins,
+			CompanyPrefixes: payload.CompanyPrefixes,
+		}
+		err = storeOrganization(mdState, previous, organization)
+		if err != nil {
+			return err
+		}
+		return mdState.AddOrganizationEvent(model.EventOrgUpdated, signer, organization)
 	default:
 		return &processor.InvalidTransactionError{
 			Msg: fmt.Sprintf("Invalid Action : '%v'", payload.Action)}
 	}
 }
 
-func validateCreate(mdState *mdata_state.MdState, gtin string) error {
+// storeProduct stores product, or deletes the product when it is nil, appends
+// the change to the product's history and emits the events describing it.
+// Family version 1.0 transactions only list the product address as input and
+// output, so their changes are not recorded in the history.
+func storeProduct(mdState *mdata_state.MdState, legacy bool, payload *mdata_payload.MdPayload, signer string, product *model.Product) error {
+	// GetProduct decodes a fresh copy of the stored product, unaffected by
+	// the changes made to product
+	previous, err := mdState.GetProduct(payload.Gtin)
+	if err != nil {
+		return err
+	}
+
+	// Every change moves the product to the next version, recorded by its
+	// history entry. A product created again after a delete continues from
+	// its last version, so that a version never refers to two products. A
+	// product without owners may have been created again by a 1.0
+	// transaction, which could not read the history, so it continues from
+	// the history too.
+	version := uint64(0)
+	if previous != nil {
+		version = previous.Version
+	}
+	if !legacy && (previous == nil || len(previous.Owners) == 0) {
+		last, err := mdState.GetHistoryVersion(payload.Gtin)
+		if err != nil {
+			return err
+		}
+		if last > version {
+			version = last
+		}
+	}
+	version++
+
+	if product == nil {
+		err = mdState.DeleteProduct(payload.Gtin)
+	} else {
+		product.Version = version
+		err = mdState.SetProduct(payload.Gtin, product)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !legacy {
+		entry := mdata_state.NewHistoryEntry(signer, payload.Action, payload.Timestamp, previous, product)
+		entry.Version = version
+		err = mdState.AppendHistory(payload.Gtin, entry)
+		if err != nil {
+			return err
+		}
+	}
+	return mdState.AddProductEvents(signer, previous, product)
+}
+
+func validateCreate(mdState *mdata_state.MdState, gtin string, signer string, legacy bool, inputs []string) error {
+	if model.ProductAddressReserved(gtin) {
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("GTIN %v hashes to an address reserved for organization records and cannot be stored", gtin)}
+	}
+	var err error
+	if legacy {
+		err = validateLegacyCreate(mdState, gtin, inputs)
+	} else {
+		err = checkPermission(mdState, legacy, signer, gtin, nil, mdata_state.RoleCreate)
+	}
+	if err != nil {
+		return err
+	}
 	product, err := mdState.GetProduct(gtin)
 	if err != nil {
 		return err
@@ -150,16 +349,7 @@ func validateCreate(mdState *mdata_state.MdState, gtin string) error {
 	return nil
 }
 
-func displayCreate(payload *mdata_payload.MdPayload, signer string) {
-	s := fmt.Sprintf("+ Signer %s created product %s +", signer[:6], payload.Gtin)
-	sLength := len(s)
-	border := "+" + strings.Repeat("-", sLength-2) + "+"
-	fmt.Println(border)
-	fmt.Println(s)
-	fmt.Println(border)
-}
-
-func validateUpdate(mdState *mdata_state.MdState, gtin string) error {
+func validateUpdate(mdState *mdata_state.MdState, gtin string, signer string, legacy bool, checkVersion bool, expectedVersion uint64) error {
 	product, err := mdState.GetProduct(gtin)
 	if err != nil {
 		return err
@@ -167,28 +357,87 @@ func validateUpdate(mdState *mdata_state.MdState, gtin string) error {
 	if product == nil {
 		return &processor.InvalidTransactionError{Msg: "Update requires an existing product"}
 	}
-	return nil
+	// Another transaction changed the product since the client read it
+	if checkVersion && product.Version != expectedVersion {
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Product %v is at version %v, update expected version %v", gtin, product.Version, expectedVersion)}
+	}
+	admin, err := isTerminalAdmin(mdState, legacy, product, signer)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		err = checkPermission(mdState, legacy, signer, gtin, product, mdata_state.RoleUpdate)
+		if err != nil {
+			return err
+		}
+	}
+	// Family version 1.0 updates predate the lifecycle, and reactivate the
+	// product whatever its state
+	if legacy {
+		return nil
+	}
+	productLifecycle, err := mdState.GetLifecycle()
+	if err != nil {
+		return err
+	}
+	// Update reactivates the product, returning it to the initial state
+	return validateTransition(mdState, product, productLifecycle.InitialState, admin)
 }
 
-func displayUpdate(payload *mdata_payload.MdPayload, signer string, product *mdata_state.Product) {
-	s := fmt.Sprintf("+ Signer %s updated product %s with attributes %s", signer[:6], product.Gtin, product.Attributes)
-	sLength := len(s)
-	border := "+" + strings.Repeat("-", sLength-2) + "+"
-	fmt.Println(border)
-	fmt.Println(s)
-	fmt.Println(border)
+func validatePatch(mdState *mdata_state.MdState, gtin string, signer string, legacy bool) error {
+	product, err := mdState.GetProduct(gtin)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return &processor.InvalidTransactionError{Msg: "Patch requires an existing product"}
+	}
+	admin, err := isTerminalAdmin(mdState, legacy, product, signer)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		err = checkPermission(mdState, legacy, signer, gtin, product, mdata_state.RoleUpdate)
+		if err != nil {
+			return err
+		}
+	}
+	return validateNotTerminal(mdState, legacy, product, admin)
 }
 
-func displayDelete(signer string, gtin string) {
-	s := fmt.Sprintf("+ Signer %s deleted product %s", signer[:6], gtin)
-	sLength := len(s)
-	border := "+" + strings.Repeat("-", sLength-2) + "+"
-	fmt.Println(border)
-	fmt.Println(s)
-	fmt.Println(border)
+func validateUnset(mdState *mdata_state.MdState, gtin string, signer string, legacy bool, keys []string) error {
+	product, err := mdState.GetProduct(gtin)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return &processor.InvalidTransactionError{Msg: "Unset requires an existing product"}
+	}
+	admin, err := isTerminalAdmin(mdState, legacy, product, signer)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		err = checkPermission(mdState, legacy, signer, gtin, product, mdata_state.RoleUpdate)
+		if err != nil {
+			return err
+		}
+	}
+	err = validateNotTerminal(mdState, legacy, product, admin)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if _, ok := product.Attributes[key]; !ok {
+			return &processor.InvalidTransactionError{
+				Msg: fmt.Sprintf("Product %v has no attribute '%v' to unset", gtin, key)}
+		}
+	}
+	return nil
 }
 
-func validateStateChange(mdState *mdata_state.MdState, gtin string, action string) error {
+func validateStateChange(mdState *mdata_state.MdState, gtin string, signer string, legacy bool, action string) error {
 	product, err := mdState.GetProduct(gtin)
 	if err != nil {
 		return err
@@ -196,20 +445,34 @@ func validateStateChange(mdState *mdata_state.MdState, gtin string, action strin
 	if product == nil {
 		return &processor.InvalidTransactionError{Msg: "Set state requires an existing product"}
 	}
+	admin, err := isTerminalAdmin(mdState, legacy, product, signer)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		err = checkPermission(mdState, legacy, signer, gtin, product, mdata_state.RoleSetState)
+		if err != nil {
+			return err
+		}
+	}
 
-	return nil
-}
+	productLifecycle, err := getLifecycle(mdState, legacy)
+	if err != nil {
+		return err
+	}
+	err = productLifecycle.ValidateState(action)
+	if err != nil {
+		return &processor.InvalidTransactionError{Msg: err.Error()}
+	}
 
-func displayStateChange(payload *mdata_payload.MdPayload, signer string, product *mdata_state.Product) {
-	s := fmt.Sprintf("+ Signer %s updated product %s state to %s+", signer[:6], product.Gtin, product.State)
-	sLength := len(s)
-	border := "+" + strings.Repeat("-", sLength-2) + "+"
-	fmt.Println(border)
-	fmt.Println(s)
-	fmt.Println(border)
+	// Family version 1.0 predates transitions, any state could be set
+	if legacy {
+		return nil
+	}
+	return validateTransition(mdState, product, action, admin)
 }
 
-func validateDelete(mdState *mdata_state.MdState, gtin string) error {
+func validateDelete(mdState *mdata_state.MdState, gtin string, signer string, legacy bool) error {
 	product, err := mdState.GetProduct(gtin)
 	if err != nil {
 		return err
@@ -217,8 +480,150 @@ func validateDelete(mdState *mdata_state.MdState, gtin string) error {
 	if product == nil {
 		return &processor.InvalidTransactionError{Msg: "Delete requires an existing product"}
 	}
-	if product.State != "INACTIVE" {
-		return &processor.InvalidTransactionError{Msg: "Delete requires an INACTIVE product. Please deactivate the product with `mdata set <GTIN> INACTIVE`."}
+	admin, err := isTerminalAdmin(mdState, legacy, product, signer)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		err = checkPermission(mdState, legacy, signer, gtin, product, mdata_state.RoleDelete)
+		if err != nil {
+			return err
+		}
+	}
+	// Family version 1.0 only ever deleted INACTIVE products
+	if legacy {
+		if product.State != lifecycle.StateInactive {
+			return &processor.InvalidTransactionError{Msg: "Delete requires an INACTIVE product. Please deactivate the product with `mdata set <GTIN> INACTIVE`."}
+		}
+		return nil
+	}
+	return validateTransition(mdState, product, lifecycle.StateDeleted, admin)
+}
+
+// isTerminalAdmin reports whether product is in a terminal state and signer is
+// an admin of the organization owning it. Such admins may move the product out
+// of the terminal state, whoever its owners are. Products without owners stay
+// out of their reach until a registrar transfers them. Family version 1.0
+// transactions cannot read the organization, so their signers are no admins.
+func isTerminalAdmin(mdState *mdata_state.MdState, legacy bool, product *model.Product, signer string) (bool, error) {
+	if legacy {
+		return false, nil
+	}
+	productLifecycle, err := mdState.GetLifecycle()
+	if err != nil {
+		return false, err
+	}
+	if !productLifecycle.IsTerminal(product.State) || len(product.Owners) == 0 {
+		return false, nil
+	}
+	return mdState.IsGtinAdmin(signer, product.Gtin)
+}
+
+// validateTransition checks that the product lifecycle allows product to move
+// to state, out of a terminal state only when admin is set
+func validateTransition(mdState *mdata_state.MdState, product *model.Product, state string, admin bool) error {
+	productLifecycle, err := mdState.GetLifecycle()
+	if err != nil {
+		return err
+	}
+	err = productLifecycle.CheckTransition(product.State, state, admin)
+	if err != nil {
+		return &processor.InvalidTransactionError{Msg: err.Error()}
 	}
 	return nil
 }
+
+// validateNotTerminal checks that product is not in a terminal state, in
+// which only an admin, when admin is set, may change its attributes. Family
+// version 1.0 transactions predate the lifecycle and are not checked.
+func validateNotTerminal(mdState *mdata_state.MdState, legacy bool, product *model.Product, admin bool) error {
+	if legacy || admin {
+		return nil
+	}
+	productLifecycle, err := mdState.GetLifecycle()
+	if err != nil {
+		return err
+	}
+	if productLifecycle.IsTerminal(product.State) {
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Product is %v, a terminal state in which only an admin of the owning organization can change it", product.State)}
+	}
+	return nil
+}
+
+// validateLegacyCreate rejects a family version 1.0 create of a GTIN whose
+// company prefix an organization owns: its products are created by the
+// organization's admins and agents with family version 2.0 transactions.
+// 1.0 clients list only the product address as input, and the processor
+// cannot read the company prefixes of a transaction that does not list
+// them; such creates stay unchecked, as they always were, until 1.0 is
+// removed from the sawtooth.validator.transaction_families setting.
+func validateLegacyCreate(mdState *mdata_state.MdState, gtin string, inputs []string) error {
+	if !listsAddresses(inputs, model.CompanyPrefixAddresses(gtin)) {
+		return nil
+	}
+	orgId, err := mdState.GetGtinOwnerId(gtin)
+	if err != nil {
+		return err
+	}
+	if orgId != "" {
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Organization %v owns the GS1 company prefix of GTIN %v, create it with family version %v", orgId, gtin, familyVersionProtobuf)}
+	}
+	return nil
+}
+
+// listsAddresses reports whether every address starts with one of inputs,
+// the address prefixes a transaction may read
+func listsAddresses(inputs []string, addresses []string) bool {
+	for _, address := range addresses {
+		listed := false
+		for _, input := range inputs {
+			if strings.HasPrefix(address, input) {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			return false
+		}
+	}
+	return true
+}
+
+// getLifecycle returns the lifecycle products follow. Family version 1.0
+// transactions only list the product address as input, so they follow
+// lifecycle.Default without reading the mdata.product.states setting.
+func getLifecycle(mdState *mdata_state.MdState, legacy bool) (*lifecycle.Lifecycle, error) {
+	if legacy {
+		return lifecycle.Default, nil
+	}
+	return mdState.GetLifecycle()
+}
+
+// checkPermission checks that signer may perform role on the product stored
+// under gtin. Family version 1.0 transactions only list the product address
+// as input, so they are checked without reading settings, organizations or
+// agents: products without owners are changed unchecked, as they always
+// were, and products with owners only by one of their owners.
+func checkPermission(mdState *mdata_state.MdState, legacy bool, signer string, gtin string, product *model.Product, role string) error {
+	if !legacy {
+		return mdState.CheckPermission(signer, gtin, product, role)
+	}
+	if product == nil || len(product.Owners) == 0 || product.IsOwner(signer) {
+		return nil
+	}
+	return &processor.InvalidTransactionError{
+		Msg: fmt.Sprintf("Signer %v is not an owner of product %v, agents change it with family version %v", signer, gtin, familyVersionProtobuf)}
+}
+
+func validateTransfer(mdState *mdata_state.MdState, gtin string, signer string, legacy bool) error {
+	product, err := mdState.GetProduct(gtin)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return &processor.InvalidTransactionError{Msg: "Transfer requires an existing product"}
+	}
+	return checkPermission(mdState, legacy, signer, gtin, product, mdata_state.RoleTransfer)
+}
@@ -0,0 +1,825 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/processor_pb2"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/setting_pb2"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/transaction_pb2"
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/lifecycle"
+	"github.com/tross-tyson/mdata_go/src/mdata_processor/mdata_payload"
+	"github.com/tross-tyson/mdata_go/src/mdata_processor/mdata_state"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"github.com/tross-tyson/mdata_go/src/protobuf/mdata_payload_pb2"
+)
+
+// Signer public keys
+var (
+	adminA    = "02" + strings.Repeat("a", 64)
+	adminB    = "02" + strings.Repeat("b", 64)
+	registrar = "03" + strings.Repeat("c", 64)
+)
+
+// newState returns state in which registrar is the only registrar
+func newState(t *testing.T) map[string][]byte {
+	data, err := proto.Marshal(&setting_pb2.Setting{
+		Entries: []*setting_pb2.Setting_Entry{{Key: model.RegistrarsSetting, Value: registrar}},
+	})
+	assert.Nil(t, err)
+	return map[string][]byte{lifecycle.SettingAddress(model.RegistrarsSetting): data}
+}
+
+// memoryContext reads and writes state as a validator would, recording the
+// types of the events added. Like a validator, it refuses addresses outside
+// the address prefixes of the transaction's inputs and outputs, when these
+// are set.
+type memoryContext struct {
+	state   map[string][]byte
+	inputs  []string
+	outputs []string
+	events  []string
+}
+
+func newMemoryContext(state map[string][]byte) *memoryContext {
+	return &memoryContext{state: state}
+}
+
+// authorized reports whether every address starts with one of prefixes, or
+// prefixes is empty
+func authorized(addresses []string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, address := range addresses {
+		found := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(address, prefix) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (self *memoryContext) GetState(addresses []string) (map[string][]byte, error) {
+	if !authorized(addresses, self.inputs) {
+		return nil, &processor.AuthorizationException{Msg: fmt.Sprint("Tried to get unauthorized address: ", addresses)}
+	}
+	results := make(map[string][]byte)
+	for _, address := range addresses {
+		if data, ok := self.state[address]; ok {
+			results[address] = data
+		}
+	}
+	return results, nil
+}
+
+func (self *memoryContext) SetState(values map[string][]byte) ([]string, error) {
+	var addresses []string
+	for address := range values {
+		addresses = append(addresses, address)
+	}
+	if !authorized(addresses, self.outputs) {
+		return nil, &processor.AuthorizationException{Msg: fmt.Sprint("Tried to set unauthorized address: ", addresses)}
+	}
+	for address, data := range values {
+		self.state[address] = data
+	}
+	return addresses, nil
+}
+
+func (self *memoryContext) DeleteState(addresses []string) ([]string, error) {
+	if !authorized(addresses, self.outputs) {
+		return nil, &processor.AuthorizationException{Msg: fmt.Sprint("Tried to delete unauthorized address: ", addresses)}
+	}
+	for _, address := range addresses {
+		delete(self.state, address)
+	}
+	return addresses, nil
+}
+
+func (self *memoryContext) AddEvent(eventType string, attributes []processor.Attribute, data []byte) error {
+	self.events = append(self.events, eventType)
+	return nil
+}
+
+// applyPayload applies payload signed by signer as a family version 2.0
+// transaction
+func applyPayload(t *testing.T, state map[string][]byte, signer string, payload *mdata_payload_pb2.MdPayload) error {
+	data, err := proto.Marshal(payload)
+	assert.Nil(t, err)
+	_, err = applyTransaction(state, familyVersionProtobuf, signer, data)
+	return err
+}
+
+// applyCsv applies the comma separated payload signed by signer as a family
+// version 1.0 transaction
+func applyCsv(state map[string][]byte, signer string, payload string) error {
+	_, err := applyTransaction(state, familyVersionCsv, signer, []byte(payload))
+	return err
+}
+
+// applyTransaction applies the transaction to state and returns the types of
+// the events it emitted. Like a validator, it keeps the changes to state only
+// when the transaction is valid.
+func applyTransaction(state map[string][]byte, familyVersion string, signer string, payload []byte) ([]string, error) {
+	return applyHeader(state, &transaction_pb2.TransactionHeader{FamilyVersion: familyVersion, SignerPublicKey: signer}, payload)
+}
+
+// applyHeader applies the transaction with header to state like
+// applyTransaction, allowing it to read its inputs and write its outputs
+// only, when they are set
+func applyHeader(state map[string][]byte, header *transaction_pb2.TransactionHeader, payload []byte) ([]string, error) {
+	request := &processor_pb2.TpProcessRequest{Header: header, Payload: payload}
+
+	changed := make(map[string][]byte)
+	for address, value := range state {
+		changed[address] = value
+	}
+	context := &memoryContext{state: changed, inputs: header.GetInputs(), outputs: header.GetOutputs()}
+	err := (&MdHandler{}).apply(request, mdata_state.NewMdState(context))
+	if err == nil {
+		for address := range state {
+			delete(state, address)
+		}
+		for address, value := range changed {
+			state[address] = value
+		}
+		return context.events, nil
+	}
+	return nil, err
+}
+
+func orgCreate(orgId string, admin string, prefixes ...string) *mdata_payload_pb2.MdPayload {
+	return &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_ORG_CREATE, OrgId: orgId,
+		Admins: []string{admin}, CompanyPrefixes: prefixes}
+}
+
+func orgUpdate(orgId string, admin string, prefixes ...string) *mdata_payload_pb2.MdPayload {
+	return &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_ORG_UPDATE, OrgId: orgId,
+		Admins: []string{admin}, CompanyPrefixes: prefixes}
+}
+
+func productCreate(gtin string) *mdata_payload_pb2.MdPayload {
+	return &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_CREATE, Gtin: gtin,
+		Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "uom", Value: "cases"}}}
+}
+
+func TestOverlappingCompanyPrefixes(t *testing.T) {
+	state := newState(t)
+	assert.Nil(t, applyPayload(t, state, registrar, orgCreate("acme", adminA, "001234")))
+
+	tests := map[string]struct {
+		signer  string
+		payload *mdata_payload_pb2.MdPayload
+		valid   bool
+	}{
+		"samePrefix":      {signer: registrar, payload: orgCreate("other", adminB, "001234")},
+		"nestedPrefix":    {signer: registrar, payload: orgCreate("other", adminB, "00123456")},
+		"enclosingPrefix": {signer: registrar, payload: orgCreate("other", adminB, "0012")},
+		"ownNestedPrefix": {signer: registrar, payload: orgUpdate("acme", adminA, "001234", "00123456"), valid: true},
+		"unrelatedPrefix": {signer: registrar, payload: orgCreate("other", adminB, "0099"), valid: true},
+	}
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		testState := make(map[string][]byte)
+		for address, value := range state {
+			testState[address] = value
+		}
+		err := applyPayload(t, testState, test.signer, test.payload)
+		if test.valid {
+			assert.Nil(t, err)
+		} else {
+			assert.IsType(t, &processor.InvalidTransactionError{}, err)
+		}
+	}
+
+	// Other organizations cannot take over GTINs under the prefix
+	assert.Nil(t, applyPayload(t, state, registrar, orgCreate("other", adminB, "0099")))
+	assert.IsType(t, &processor.InvalidTransactionError{}, applyPayload(t, state, registrar, orgUpdate("other", adminB, "0099", "0012345")))
+	assert.Nil(t, applyPayload(t, state, adminA, productCreate("00012345600012")))
+}
+
+func TestRegistrars(t *testing.T) {
+	state := newState(t)
+	assert.Nil(t, applyPayload(t, state, registrar, orgCreate("acme", adminA, "001234")))
+	assert.Nil(t, applyPayload(t, state, adminA, productCreate("00012345600012")))
+
+	// A copy of the product stored without owners, as before owners were recorded
+	mdState := mdata_state.NewMdState(newMemoryContext(state))
+	product, err := mdState.GetProduct("00012345600012")
+	assert.Nil(t, err)
+	product.Gtin = "00012345600029"
+	product.Owners = nil
+	assert.Nil(t, mdState.SetProduct(product.Gtin, product))
+
+	tests := map[string]struct {
+		signer  string
+		payload *mdata_payload_pb2.MdPayload
+		valid   bool
+	}{
+		"adminCreatesOrganization":     {signer: adminA, payload: orgCreate("other", adminA, "0099")},
+		"registrarCreatesOrganization": {signer: registrar, payload: orgCreate("other", adminB, "0099"), valid: true},
+		"adminAssignsPrefix":           {signer: adminA, payload: orgUpdate("acme", adminA, "001234", "0099")},
+		"registrarAssignsPrefix":       {signer: registrar, payload: orgUpdate("acme", adminA, "001234", "0099"), valid: true},
+		"adminChangesAdmins":           {signer: adminA, payload: orgUpdate("acme", adminB, "001234"), valid: true},
+		"registrarChangesAdmins":       {signer: registrar, payload: orgUpdate("acme", adminB, "001234")},
+		"adminUpdatesLegacyProduct": {signer: adminA, payload: &mdata_payload_pb2.MdPayload{
+			Action: mdata_payload_pb2.MdPayload_SET, Gtin: "00012345600029", State: "INACTIVE"}},
+		"registrarTransfersLegacyProduct": {signer: registrar, payload: &mdata_payload_pb2.MdPayload{
+			Action: mdata_payload_pb2.MdPayload_TRANSFER, Gtin: "00012345600029", NewOwner: adminA}, valid: true},
+	}
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		testState := make(map[string][]byte)
+		for address, value := range state {
+			testState[address] = value
+		}
+		err := applyPayload(t, testState, test.signer, test.payload)
+		if test.valid {
+			assert.Nil(t, err)
+		} else {
+			assert.IsType(t, &processor.InvalidTransactionError{}, err)
+		}
+	}
+}
+
+func TestLegacyTransactions(t *testing.T) {
+	state := newState(t)
+	assert.Nil(t, applyPayload(t, state, registrar, orgCreate("acme", adminA, "001234")))
+
+	// Products created by 1.0 transactions have no owners and anyone changes them
+	assert.Nil(t, applyCsv(state, adminB, "create,00012345600012,uom=cases,"))
+	assert.Nil(t, applyCsv(state, adminA, "update,00012345600012,uom=pallets,"))
+	product, err := mdata_state.NewMdState(newMemoryContext(state)).GetProduct("00012345600012")
+	assert.Nil(t, err)
+	assert.Equal(t, adminB, product.Creator)
+	assert.Empty(t, product.Owners)
+
+	// Organization admins cannot take over products without owners, a registrar transfers them
+	transfer := &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_TRANSFER, Gtin: "00012345600012", NewOwner: adminA}
+	assert.IsType(t, &processor.InvalidTransactionError{}, applyPayload(t, state, adminA, transfer))
+	assert.Nil(t, applyPayload(t, state, registrar, transfer))
+
+	// Once owned, 1.0 transactions are only accepted from the owners, agents
+	// act through 2.0 ones
+	assert.IsType(t, &processor.InvalidTransactionError{}, applyCsv(state, adminB, "update,00012345600012,uom=cases,"))
+	assert.Nil(t, applyPayload(t, state, adminA, &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_AGENT_CREATE,
+		PublicKey: agentKey, OrgId: "acme", Roles: []string{mdata_state.RoleUpdate}}))
+	assert.IsType(t, &processor.InvalidTransactionError{}, applyCsv(state, agentKey, "update,00012345600012,uom=cases,"))
+	assert.Nil(t, applyCsv(state, adminA, "update,00012345600012,uom=cases,"))
+}
+
+func TestLegacyInputs(t *testing.T) {
+	gtin := "00012345600012"
+	address := model.ProductAddress(gtin)
+	state := newState(t)
+	assert.Nil(t, applyPayload(t, state, registrar, orgCreate("acme", adminA, "001234")))
+	apply := func(payload string) error {
+		// 1.0 clients list the product address only
+		_, err := applyHeader(state, &transaction_pb2.TransactionHeader{FamilyVersion: familyVersionCsv, SignerPublicKey: adminB,
+			Inputs: []string{address}, Outputs: []string{address}}, []byte(payload))
+		return err
+	}
+
+	assert.Nil(t, apply("create,"+gtin+",uom=cases,"))
+	assert.Nil(t, apply("update,"+gtin+",uom=pallets,"))
+	assert.Nil(t, apply("set,"+gtin+",,INACTIVE"))
+	assert.Nil(t, apply("delete,"+gtin+",,"))
+	mdState := mdata_state.NewMdState(newMemoryContext(state))
+	product, err := mdState.GetProduct(gtin)
+	assert.Nil(t, err)
+	assert.Nil(t, product)
+	history, err := mdState.GetHistory(gtin)
+	assert.Nil(t, err)
+	assert.Empty(t, history)
+
+	// A product created again by a 1.0 transaction continues from its history
+	// once a 2.0 transaction changes it
+	assert.Nil(t, applyPayload(t, state, adminA, productCreate(gtin)))
+	assert.Nil(t, applyPayload(t, state, adminA, &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_SET, Gtin: gtin, State: "INACTIVE"}))
+	assert.Nil(t, applyPayload(t, state, adminA, &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_DELETE, Gtin: gtin}))
+	assert.Nil(t, apply("create,"+gtin+",uom=cases,"))
+	assert.Nil(t, applyPayload(t, state, registrar, &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_TRANSFER, Gtin: gtin, NewOwner: adminA}))
+	product, err = mdata_state.NewMdState(newMemoryContext(state)).GetProduct(gtin)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), product.Version)
+}
+
+func TestLegacyCreateOwnedPrefix(t *testing.T) {
+	state := newState(t)
+	assert.Nil(t, applyPayload(t, state, registrar, orgCreate("acme", adminA, "001234")))
+	create := func(gtin string) error {
+		address := model.ProductAddress(gtin)
+		_, err := applyHeader(state, &transaction_pb2.TransactionHeader{FamilyVersion: familyVersionCsv, SignerPublicKey: adminB,
+			Inputs: append([]string{address}, model.CompanyPrefixAddresses(gtin)...), Outputs: []string{address}},
+			[]byte("create,"+gtin+",uom=cases,"))
+		return err
+	}
+
+	// A 1.0 create that lets the processor read the company prefixes cannot
+	// take a GTIN of another organization
+	assert.IsType(t, &processor.InvalidTransactionError{}, create("00012345600012"))
+	assert.Nil(t, create("00099900000012"))
+}
+
+func TestLegacyTransitions(t *testing.T) {
+	gtin := "00012345600012"
+	state := newState(t)
+	assert.Nil(t, applyCsv(state, adminB, "create,"+gtin+",uom=cases,"))
+
+	// 1.0 transactions move products between states as they always did
+	assert.IsType(t, &processor.InvalidTransactionError{}, applyCsv(state, adminB, "delete,"+gtin+",,"))
+	assert.Nil(t, applyCsv(state, adminB, "set,"+gtin+",,DISCONTINUED"))
+	assert.Nil(t, applyCsv(state, adminB, "update,"+gtin+",uom=pallets,"))
+	product, err := mdata_state.NewMdState(newMemoryContext(state)).GetProduct(gtin)
+	assert.Nil(t, err)
+	assert.Equal(t, lifecycle.StateActive, product.State)
+	assert.Nil(t, applyCsv(state, adminB, "set,"+gtin+",,INACTIVE"))
+	assert.Nil(t, applyCsv(state, adminB, "delete,"+gtin+",,"))
+}
+
+func TestExpectedVersionZero(t *testing.T) {
+	gtin := "00012345600012"
+	state := newState(t)
+	data, err := model.EncodeProducts([]*model.Product{{Gtin: gtin, State: lifecycle.StateActive, Creator: adminA, Owners: []string{adminA},
+		Attributes: model.Attributes{"uom": "cases"}}})
+	assert.Nil(t, err)
+	state[model.ProductAddress(gtin)] = data
+	update := func(color string) *mdata_payload_pb2.MdPayload {
+		return &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UPDATE, Gtin: gtin,
+			Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "color", Value: color}}, CheckVersion: true}
+	}
+
+	// Products owned before versions were recorded are at version 0, which an
+	// update can expect like any other version
+	assert.Nil(t, applyPayload(t, state, adminA, update("red")))
+	assert.IsType(t, &processor.InvalidTransactionError{}, applyPayload(t, state, adminA, update("blue")))
+}
+
+func TestLegacyCheckDigit(t *testing.T) {
+	gtin := "00012345600013"
+	state := newState(t)
+
+	// 1.0 transactions stored any 14 digits, 2.0 ones change such products
+	// but do not create them
+	assert.Nil(t, applyCsv(state, adminB, "create,"+gtin+",uom=cases,"))
+	assert.Nil(t, applyPayload(t, state, registrar, &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_TRANSFER, Gtin: gtin, NewOwner: adminA}))
+	assert.Nil(t, applyPayload(t, state, adminA, &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UPDATE, Gtin: gtin,
+		Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "uom", Value: "pallets"}}}))
+	product, err := mdata_state.NewMdState(newMemoryContext(state)).GetProduct(gtin)
+	assert.Nil(t, err)
+	assert.Equal(t, "pallets", product.Attributes["uom"])
+	assert.IsType(t, &processor.InvalidTransactionError{}, applyPayload(t, state, adminA, productCreate("00012345600023")))
+}
+
+func TestLegacyLifecycle(t *testing.T) {
+	state := newState(t)
+	data, err := proto.Marshal(&setting_pb2.Setting{
+		Entries: []*setting_pb2.Setting_Entry{{Key: lifecycle.SettingKey, Value: "DRAFT:PUBLISHED;PUBLISHED:DRAFT"}},
+	})
+	assert.Nil(t, err)
+	state[lifecycle.SettingAddress(lifecycle.SettingKey)] = data
+
+	// 1.0 transactions follow the default lifecycle whatever the setting
+	assert.Nil(t, applyCsv(state, adminB, "create,00012345600012,uom=cases,"))
+	product, err := mdata_state.NewMdState(newMemoryContext(state)).GetProduct("00012345600012")
+	assert.Nil(t, err)
+	assert.Equal(t, lifecycle.StateActive, product.State)
+	assert.IsType(t, &processor.InvalidTransactionError{}, applyCsv(state, adminB, "set,00012345600012,,PUBLISHED"))
+	assert.Nil(t, applyCsv(state, adminB, "set,00012345600012,,INACTIVE"))
+}
+
+func TestTerminalStateAdminOverride(t *testing.T) {
+	gtin := "00012345600012"
+	attributes := []*mdata_payload_pb2.MdPayload_Attribute{{Key: "uom", Value: "pallets"}}
+	setState := func(state string) *mdata_payload_pb2.MdPayload {
+		return &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_SET, Gtin: gtin, State: state}
+	}
+
+	state := newState(t)
+	assert.Nil(t, applyPayload(t, state, registrar, orgCreate("acme", adminA, "001234")))
+	assert.Nil(t, applyPayload(t, state, adminA, productCreate(gtin)))
+	assert.Nil(t, applyPayload(t, state, adminA, &mdata_payload_pb2.MdPayload{
+		Action: mdata_payload_pb2.MdPayload_TRANSFER, Gtin: gtin, NewOwner: adminB}))
+	assert.Nil(t, applyPayload(t, state, adminB, setState("INACTIVE")))
+	assert.Nil(t, applyPayload(t, state, adminB, setState("DISCONTINUED")))
+
+	tests := map[string]struct {
+		signer  string
+		payload *mdata_payload_pb2.MdPayload
+		valid   bool
+	}{
+		"ownerSetsState":    {signer: adminB, payload: setState("ACTIVE")},
+		"ownerDeletes":      {signer: adminB, payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_DELETE, Gtin: gtin}},
+		"adminSetsState":    {signer: adminA, payload: setState("ACTIVE"), valid: true},
+		"adminUpdates":      {signer: adminA, payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UPDATE, Gtin: gtin, Attributes: attributes}, valid: true},
+		"adminDeletes":      {signer: adminA, payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_DELETE, Gtin: gtin}, valid: true},
+		"ownerPatches":      {signer: adminB, payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_PATCH, Gtin: gtin, Attributes: attributes}},
+		"ownerUnsets":       {signer: adminB, payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UNSET_ATTRIBUTES, Gtin: gtin, RemoveKeys: []string{"uom"}}},
+		"adminPatches":      {signer: adminA, payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_PATCH, Gtin: gtin, Attributes: attributes}, valid: true},
+		"adminUnsets":       {signer: adminA, payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UNSET_ATTRIBUTES, Gtin: gtin, RemoveKeys: []string{"uom"}}, valid: true},
+		"adminTransfers":    {signer: adminA, payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_TRANSFER, Gtin: gtin, NewOwner: adminA}},
+		"strangerSetsState": {signer: registrar, payload: setState("ACTIVE")},
+	}
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		testState := make(map[string][]byte)
+		for address, value := range state {
+			testState[address] = value
+		}
+		err := applyPayload(t, testState, test.signer, test.payload)
+		if test.valid {
+			assert.Nil(t, err)
+		} else {
+			assert.IsType(t, &processor.InvalidTransactionError{}, err)
+		}
+	}
+}
+
+// agentKey is an agent of acme, the organization newProductState creates
+var agentKey = "03" + strings.Repeat("d", 64)
+
+// newProductState returns state holding organization acme, owning company
+// prefix 001234 and administered by adminA, its agent agentKey, allowed to
+// update products and set their state, and product 00012345600012 created by
+// adminA with attribute uom=cases
+func newProductState(t *testing.T) map[string][]byte {
+	state := newState(t)
+	assert.Nil(t, applyPayload(t, state, registrar, orgCreate("acme", adminA, "001234")))
+	assert.Nil(t, applyPayload(t, state, adminA, &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_AGENT_CREATE,
+		PublicKey: agentKey, OrgId: "acme", Roles: []string{mdata_state.RoleUpdate, mdata_state.RoleSetState}}))
+	assert.Nil(t, applyPayload(t, state, adminA, productCreate("00012345600012")))
+	return state
+}
+
+func TestApplyProduct(t *testing.T) {
+	gtin := "00012345600012"
+	attributes := func(pairs ...string) []*mdata_payload_pb2.MdPayload_Attribute {
+		var result []*mdata_payload_pb2.MdPayload_Attribute
+		for i := 0; i < len(pairs); i += 2 {
+			result = append(result, &mdata_payload_pb2.MdPayload_Attribute{Key: pairs[i], Value: pairs[i+1]})
+		}
+		return result
+	}
+	setState := func(state string) *mdata_payload_pb2.MdPayload {
+		return &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_SET, Gtin: gtin, State: state}
+	}
+	inactive := []*mdata_payload_pb2.MdPayload{setState("INACTIVE")}
+
+	tests := map[string]struct {
+		setup   []*mdata_payload_pb2.MdPayload // applied first, signed by adminA
+		signer  string
+		payload *mdata_payload_pb2.MdPayload
+		err     error
+		events  []string
+		product *model.Product // nil when the product is deleted
+	}{
+		"create": {
+			signer:  adminA,
+			payload: productCreate("00012345600029"),
+			events:  []string{model.EventProductCreated},
+			product: &model.Product{Gtin: "00012345600029", Version: 1, State: "ACTIVE", Creator: adminA, Owners: []string{adminA},
+				Attributes: model.Attributes{"uom": "cases"}},
+		},
+		"createAfterDelete": { // Versions continue from the deleted product's history
+			setup:   []*mdata_payload_pb2.MdPayload{setState("INACTIVE"), {Action: mdata_payload_pb2.MdPayload_DELETE, Gtin: gtin}},
+			signer:  adminA,
+			payload: productCreate(gtin),
+			events:  []string{model.EventProductCreated},
+			product: &model.Product{Gtin: gtin, Version: 4, State: "ACTIVE", Creator: adminA, Owners: []string{adminA},
+				Attributes: model.Attributes{"uom": "cases"}},
+		},
+		"createExisting": {
+			signer:  adminA,
+			payload: productCreate(gtin),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"createUnownedPrefix": {
+			signer:  adminA,
+			payload: productCreate("00099900000012"),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"createByStranger": {
+			signer:  adminB,
+			payload: productCreate("00012345600029"),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"update": {
+			signer:  adminA,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UPDATE, Gtin: gtin, Attributes: attributes("color", "red"), ExpectedVersion: 1},
+			events:  []string{model.EventProductUpdated},
+			product: &model.Product{Gtin: gtin, Version: 2, State: "ACTIVE", Creator: adminA, Owners: []string{adminA},
+				Attributes: model.Attributes{"color": "red"}},
+		},
+		"updateExpectingStaleVersion": {
+			setup:   inactive,
+			signer:  adminA,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UPDATE, Gtin: gtin, Attributes: attributes("color", "red"), ExpectedVersion: 1},
+			err:     &processor.InvalidTransactionError{},
+		},
+		"updateExpectingVersionZero": {
+			signer:  adminA,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UPDATE, Gtin: gtin, Attributes: attributes("color", "red"), CheckVersion: true},
+			err:     &processor.InvalidTransactionError{},
+		},
+		"updateReactivates": {
+			setup:   inactive,
+			signer:  adminA,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UPDATE, Gtin: gtin, Attributes: attributes("uom", "cases"), ExpectedVersion: 2},
+			events:  []string{model.EventStateChanged},
+			product: &model.Product{Gtin: gtin, Version: 3, State: "ACTIVE", Creator: adminA, Owners: []string{adminA},
+				Attributes: model.Attributes{"uom": "cases"}},
+		},
+		"updateMissing": {
+			signer:  adminA,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UPDATE, Gtin: "00012345600029", Attributes: attributes("uom", "cases")},
+			err:     &processor.InvalidTransactionError{},
+		},
+		"patch": {
+			signer:  adminA,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_PATCH, Gtin: gtin, Attributes: attributes("color", "red")},
+			events:  []string{model.EventProductUpdated},
+			product: &model.Product{Gtin: gtin, Version: 2, State: "ACTIVE", Creator: adminA, Owners: []string{adminA},
+				Attributes: model.Attributes{"uom": "cases", "color": "red"}},
+		},
+		"patchByAgent": {
+			signer:  agentKey,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_PATCH, Gtin: gtin, Attributes: attributes("uom", "pallets")},
+			events:  []string{model.EventProductUpdated},
+			product: &model.Product{Gtin: gtin, Version: 2, State: "ACTIVE", Creator: adminA, Owners: []string{adminA},
+				Attributes: model.Attributes{"uom": "pallets"}},
+		},
+		"patchByStranger": {
+			signer:  adminB,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_PATCH, Gtin: gtin, Attributes: attributes("uom", "pallets")},
+			err:     &processor.InvalidTransactionError{},
+		},
+		"unset": {
+			signer:  adminA,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UNSET_ATTRIBUTES, Gtin: gtin, RemoveKeys: []string{"uom"}},
+			events:  []string{model.EventProductUpdated},
+			product: &model.Product{Gtin: gtin, Version: 2, State: "ACTIVE", Creator: adminA, Owners: []string{adminA},
+				Attributes: model.Attributes{}},
+		},
+		"unsetMissingAttribute": {
+			signer:  adminA,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_UNSET_ATTRIBUTES, Gtin: gtin, RemoveKeys: []string{"color"}},
+			err:     &processor.InvalidTransactionError{},
+		},
+		"setState": {
+			signer:  agentKey,
+			payload: setState("INACTIVE"),
+			events:  []string{model.EventStateChanged},
+			product: &model.Product{Gtin: gtin, Version: 2, State: "INACTIVE", Creator: adminA, Owners: []string{adminA},
+				Attributes: model.Attributes{"uom": "cases"}},
+		},
+		"setStateInvalidTransition": {
+			signer:  adminA,
+			payload: setState("DISCONTINUED"),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"setUnknownState": {
+			signer:  adminA,
+			payload: setState("RECALLED"),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"delete": {
+			setup:   inactive,
+			signer:  adminA,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_DELETE, Gtin: gtin},
+			events:  []string{model.EventProductDeleted},
+		},
+		"deleteActive": {
+			signer:  adminA,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_DELETE, Gtin: gtin},
+			err:     &processor.InvalidTransactionError{},
+		},
+		"deleteByAgentWithoutRole": {
+			setup:   inactive,
+			signer:  agentKey,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_DELETE, Gtin: gtin},
+			err:     &processor.InvalidTransactionError{},
+		},
+		"transfer": {
+			signer:  adminA,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_TRANSFER, Gtin: gtin, NewOwner: adminB},
+			events:  []string{model.EventProductUpdated},
+			product: &model.Product{Gtin: gtin, Version: 2, State: "ACTIVE", Creator: adminA, Owners: []string{adminB},
+				Attributes: model.Attributes{"uom": "cases"}},
+		},
+		"transferByAgentWithoutRole": {
+			signer:  agentKey,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_TRANSFER, Gtin: gtin, NewOwner: agentKey},
+			err:     &processor.InvalidTransactionError{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		state := newProductState(t)
+		for _, payload := range test.setup {
+			assert.Nil(t, applyPayload(t, state, adminA, payload))
+		}
+		before := make(map[string][]byte)
+		for address, value := range state {
+			before[address] = value
+		}
+
+		data, err := proto.Marshal(test.payload)
+		assert.Nil(t, err)
+		payload, err := mdata_payload.FromProtobuf(data)
+		assert.Nil(t, err)
+		events, err := applyTransaction(state, familyVersionProtobuf, test.signer, data)
+		if test.err != nil {
+			assert.IsType(t, test.err, err)
+			assert.Equal(t, before, state)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, test.events, events)
+
+		mdState := mdata_state.NewMdState(newMemoryContext(state))
+		product, err := mdState.GetProduct(test.payload.Gtin)
+		assert.Nil(t, err)
+		assert.Equal(t, test.product, product)
+
+		// Every change is appended to the product's history
+		history, err := mdState.GetHistory(test.payload.Gtin)
+		assert.Nil(t, err)
+		if assert.NotEmpty(t, history) {
+			entry := history[len(history)-1]
+			assert.Equal(t, test.signer, entry.Signer)
+			assert.Equal(t, payload.Action, entry.Action)
+			if product != nil {
+				assert.Equal(t, product.Version, entry.Version)
+				assert.Equal(t, product.State, entry.State)
+			}
+		}
+	}
+}
+
+func TestAgentCreatedProduct(t *testing.T) {
+	gtin := "00012345600029"
+	agentUpdate := func(roles ...string) *mdata_payload_pb2.MdPayload {
+		return &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_AGENT_UPDATE, PublicKey: agentKey, Roles: roles}
+	}
+	transfer := &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_TRANSFER, Gtin: gtin, NewOwner: agentKey}
+
+	state := newProductState(t)
+	assert.Nil(t, applyPayload(t, state, adminA, agentUpdate(mdata_state.RoleCreate, mdata_state.RoleUpdate)))
+	assert.Nil(t, applyPayload(t, state, agentKey, productCreate(gtin)))
+
+	// The organization's admins own the product, not the agent creating it
+	product, err := mdata_state.NewMdState(newMemoryContext(state)).GetProduct(gtin)
+	assert.Nil(t, err)
+	assert.Equal(t, agentKey, product.Creator)
+	assert.Equal(t, []string{adminA}, product.Owners)
+	assert.IsType(t, &processor.InvalidTransactionError{}, applyPayload(t, state, agentKey, transfer))
+
+	// Taking the agent's roles away leaves it no control over the product
+	assert.Nil(t, applyPayload(t, state, adminA, agentUpdate()))
+	assert.IsType(t, &processor.InvalidTransactionError{}, applyPayload(t, state, agentKey, &mdata_payload_pb2.MdPayload{
+		Action: mdata_payload_pb2.MdPayload_PATCH, Gtin: gtin,
+		Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "uom", Value: "pallets"}}}))
+	assert.Nil(t, applyPayload(t, state, adminA, transfer))
+}
+
+func TestApplyOrganization(t *testing.T) {
+	agentCreate := func(publicKey string, roles ...string) *mdata_payload_pb2.MdPayload {
+		return &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_AGENT_CREATE, PublicKey: publicKey, OrgId: "acme", Roles: roles}
+	}
+	agentUpdate := func(publicKey string, roles ...string) *mdata_payload_pb2.MdPayload {
+		return &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_AGENT_UPDATE, PublicKey: publicKey, Roles: roles}
+	}
+	acme := &mdata_state.Organization{OrgId: "acme", Admins: []string{adminA}, CompanyPrefixes: []string{"001234"}}
+
+	tests := map[string]struct {
+		signer       string
+		payload      *mdata_payload_pb2.MdPayload
+		err          error
+		events       []string
+		organization *mdata_state.Organization // acme after the transaction
+		agent        *mdata_state.Agent        // the payload's agent after the transaction
+	}{
+		"agentCreate": {
+			signer:       adminA,
+			payload:      agentCreate(adminB, mdata_state.RoleCreate),
+			organization: acme,
+			agent:        &mdata_state.Agent{PublicKey: adminB, OrgId: "acme", Roles: []string{mdata_state.RoleCreate}},
+			events:       []string{model.EventAgentCreated},
+		},
+		"agentCreateByStranger": {
+			signer:  adminB,
+			payload: agentCreate(adminB, mdata_state.RoleCreate),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"agentCreateByAgent": {
+			signer:  agentKey,
+			payload: agentCreate(adminB, mdata_state.RoleCreate),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"agentCreateExisting": {
+			signer:  adminA,
+			payload: agentCreate(agentKey, mdata_state.RoleCreate),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"agentUpdate": {
+			signer:       adminA,
+			payload:      agentUpdate(agentKey, mdata_state.RoleDelete),
+			organization: acme,
+			agent:        &mdata_state.Agent{PublicKey: agentKey, OrgId: "acme", Roles: []string{mdata_state.RoleDelete}},
+			events:       []string{model.EventAgentUpdated},
+		},
+		"agentUpdateByAgent": {
+			signer:  agentKey,
+			payload: agentUpdate(agentKey, mdata_state.RoleDelete),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"agentUpdateMissing": {
+			signer:  adminA,
+			payload: agentUpdate(adminB, mdata_state.RoleDelete),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"orgCreateExisting": {
+			signer:  registrar,
+			payload: orgCreate("acme", adminB, "0099"),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"orgUpdateName": {
+			signer:       adminA,
+			payload:      &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_ORG_UPDATE, OrgId: "acme", OrgName: "Acme", Admins: []string{adminA}, CompanyPrefixes: []string{"001234"}},
+			organization: &mdata_state.Organization{OrgId: "acme", Name: "Acme", Admins: []string{adminA}, CompanyPrefixes: []string{"001234"}},
+			events:       []string{model.EventOrgUpdated},
+		},
+		"orgUpdateAdmins": {
+			signer:       adminA,
+			payload:      &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_ORG_UPDATE, OrgId: "acme", Admins: []string{adminA, adminB}, CompanyPrefixes: []string{"001234"}},
+			organization: &mdata_state.Organization{OrgId: "acme", Admins: []string{adminA, adminB}, CompanyPrefixes: []string{"001234"}},
+			events:       []string{model.EventOrgUpdated},
+		},
+		"orgUpdateByAgent": {
+			signer:  agentKey,
+			payload: &mdata_payload_pb2.MdPayload{Action: mdata_payload_pb2.MdPayload_ORG_UPDATE, OrgId: "acme", OrgName: "Acme", Admins: []string{adminA}, CompanyPrefixes: []string{"001234"}},
+			err:     &processor.InvalidTransactionError{},
+		},
+		"orgUpdateMissing": {
+			signer:  registrar,
+			payload: orgUpdate("other", adminB, "0099"),
+			err:     &processor.InvalidTransactionError{},
+		},
+		"orgReleasesPrefix": {
+			signer:       registrar,
+			payload:      orgUpdate("acme", adminA, "0099"),
+			organization: &mdata_state.Organization{OrgId: "acme", Admins: []string{adminA}, CompanyPrefixes: []string{"0099"}},
+			events:       []string{model.EventOrgUpdated},
+		},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		state := newProductState(t)
+		before := make(map[string][]byte)
+		for address, value := range state {
+			before[address] = value
+		}
+
+		data, err := proto.Marshal(test.payload)
+		assert.Nil(t, err)
+		events, err := applyTransaction(state, familyVersionProtobuf, test.signer, data)
+		if test.err != nil {
+			assert.IsType(t, test.err, err)
+			assert.Equal(t, before, state)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, test.events, events)
+
+		mdState := mdata_state.NewMdState(newMemoryContext(state))
+		organization, err := mdState.GetOrganization("acme")
+		assert.Nil(t, err)
+		assert.Equal(t, test.organization, organization)
+		if test.agent != nil {
+			agent, err := mdState.GetAgent(test.agent.PublicKey)
+			assert.Nil(t, err)
+			assert.Equal(t, test.agent, agent)
+		}
+
+		// The GTINs under acme's company prefixes, and only those, are its own
+		owner, err := mdState.GetGtinOwner("00012345600012")
+		assert.Nil(t, err)
+		if len(test.organization.CompanyPrefixes) > 0 && test.organization.CompanyPrefixes[0] == "001234" {
+			assert.Equal(t, test.organization, owner)
+		} else {
+			assert.Nil(t, owner)
+		}
+	}
+}
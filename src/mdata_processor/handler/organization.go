@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/tross-tyson/mdata_go/src/mdata_processor/mdata_payload"
+	"github.com/tross-tyson/mdata_go/src/mdata_processor/mdata_state"
+)
+
+func validateOrgCreate(mdState *mdata_state.MdState, payload *mdata_payload.MdPayload, signer string) error {
+	err := validateRegistrar(mdState, signer)
+	if err != nil {
+		return err
+	}
+	organization, err := mdState.GetOrganization(payload.OrgId)
+	if err != nil {
+		return err
+	}
+	if organization != nil {
+		return &processor.InvalidTransactionError{Msg: fmt.Sprintf("Organization %v already exists", payload.OrgId)}
+	}
+
+	return validateCompanyPrefixes(mdState, payload)
+}
+
+// validateOrgUpdate checks that a registrar signs changes to the company
+// prefixes of the organization and one of its admins changes to its name or
+// admins
+func validateOrgUpdate(mdState *mdata_state.MdState, payload *mdata_payload.MdPayload, signer string) error {
+	organization, err := mdState.GetOrganization(payload.OrgId)
+	if err != nil {
+		return err
+	}
+	if organization == nil {
+		return &processor.InvalidTransactionError{Msg: fmt.Sprintf("Organization %v does not exist", payload.OrgId)}
+	}
+
+	if organization.Name != payload.OrgName || !sameStrings(organization.Admins, payload.Admins) {
+		if !organization.IsAdmin(signer) {
+			return &processor.InvalidTransactionError{
+				Msg: fmt.Sprintf("Signer %v is not an admin of organization %v", signer, payload.OrgId)}
+		}
+	}
+	if !sameStrings(organization.CompanyPrefixes, payload.CompanyPrefixes) {
+		err = validateRegistrar(mdState, signer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return validateCompanyPrefixes(mdState, payload)
+}
+
+// validateRegistrar checks that signer is listed by the mdata.org.registrars
+// setting
+func validateRegistrar(mdState *mdata_state.MdState, signer string) error {
+	registrar, err := mdState.IsRegistrar(signer)
+	if err != nil {
+		return err
+	}
+	if !registrar {
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Signer %v is not a registrar", signer)}
+	}
+	return nil
+}
+
+// sameStrings reports whether a and b hold the same strings, in any order
+func sameStrings(a []string, b []string) bool {
+	counts := make(map[string]int)
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		counts[s]--
+	}
+	for _, count := range counts {
+		if count != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// validateCompanyPrefixes checks that no other organization owns the company
+// prefixes claimed by the payload, a shorter prefix they start with or a
+// longer one starting with them. The longest owned prefix of a GTIN decides
+// its owner, so overlapping prefixes would hand GTINs to another organization.
+func validateCompanyPrefixes(mdState *mdata_state.MdState, payload *mdata_payload.MdPayload) error {
+	for _, prefix := range payload.CompanyPrefixes {
+		overlap, owner, err := mdState.GetOverlappingPrefix(prefix, payload.OrgId)
+		if err != nil {
+			return err
+		}
+		if owner == "" {
+			continue
+		}
+		if overlap == prefix {
+			return &processor.InvalidTransactionError{
+				Msg: fmt.Sprintf("GS1 company prefix %v is owned by organization %v", prefix, owner)}
+		}
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("GS1 company prefix %v overlaps prefix %v owned by organization %v", prefix, overlap, owner)}
+	}
+	return nil
+}
+
+// storeOrganization writes organization and points each of its company
+// prefixes at it, releasing the prefixes previous owned that it no longer does
+func storeOrganization(mdState *mdata_state.MdState, previous *mdata_state.Organization, organization *mdata_state.Organization) error {
+	if previous != nil {
+		kept := make(map[string]bool)
+		for _, prefix := range organization.CompanyPrefixes {
+			kept[prefix] = true
+		}
+		for _, prefix := range previous.CompanyPrefixes {
+			if !kept[prefix] {
+				err := mdState.DeleteCompanyPrefix(prefix)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, prefix := range organization.CompanyPrefixes {
+		err := mdState.SetCompanyPrefixOwner(prefix, organization.OrgId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return mdState.SetOrganization(organization.OrgId, organization)
+}
+
+func validateAgentCreate(mdState *mdata_state.MdState, payload *mdata_payload.MdPayload, signer string) error {
+	err := validateOrgAdmin(mdState, payload.OrgId, signer)
+	if err != nil {
+		return err
+	}
+	agent, err := mdState.GetAgent(payload.PublicKey)
+	if err != nil {
+		return err
+	}
+	if agent != nil {
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Agent %v already exists in organization %v", payload.PublicKey, agent.OrgId)}
+	}
+	return nil
+}
+
+func validateAgentUpdate(mdState *mdata_state.MdState, payload *mdata_payload.MdPayload, signer string) error {
+	agent, err := mdState.GetAgent(payload.PublicKey)
+	if err != nil {
+		return err
+	}
+	if agent == nil {
+		return &processor.InvalidTransactionError{Msg: fmt.Sprintf("Agent %v does not exist", payload.PublicKey)}
+	}
+	return validateOrgAdmin(mdState, agent.OrgId, signer)
+}
+
+// validateOrgAdmin checks that orgId exists and signer is one of its admins
+func validateOrgAdmin(mdState *mdata_state.MdState, orgId string, signer string) error {
+	organization, err := mdState.GetOrganization(orgId)
+	if err != nil {
+		return err
+	}
+	if organization == nil {
+		return &processor.InvalidTransactionError{Msg: fmt.Sprintf("Organization %v does not exist", orgId)}
+	}
+	if !organization.IsAdmin(signer) {
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Signer %v is not an admin of organization %v", signer, orgId)}
+	}
+	return nil
+}
@@ -1,8 +1,15 @@
 package mdata_payload
 
 import (
+	"encoding/hex"
 	"fmt"
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/tross-tyson/mdata_go/src/gs1"
+	"github.com/tross-tyson/mdata_go/src/lifecycle"
+	"github.com/tross-tyson/mdata_go/src/mdata_processor/mdata_state"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"github.com/tross-tyson/mdata_go/src/protobuf/mdata_payload_pb2"
 	"reflect"
 	"strconv"
 	"strings"
@@ -13,6 +20,38 @@ type MdPayload struct {
 	Gtin       string
 	Attributes []string
 	State      string
+	Timestamp  int64
+	RemoveKeys []string
+	// Organization actions
+	OrgId           string
+	OrgName         string
+	Admins          []string
+	CompanyPrefixes []string
+	// Public key a transfer hands the product to
+	NewOwner string
+	// Agent actions
+	PublicKey string
+	Roles     []string
+	// Version an update expects the product to be at, checked when
+	// CheckVersion is set
+	ExpectedVersion uint64
+	CheckVersion    bool
+}
+
+// Protobuf actions (family version 2.0) mapped onto the verbs used by the
+// comma separated payload (family version 1.0)
+var actions = map[mdata_payload_pb2.MdPayload_Action]string{
+	mdata_payload_pb2.MdPayload_CREATE:           "create",
+	mdata_payload_pb2.MdPayload_UPDATE:           "update",
+	mdata_payload_pb2.MdPayload_DELETE:           "delete",
+	mdata_payload_pb2.MdPayload_SET:              "set",
+	mdata_payload_pb2.MdPayload_PATCH:            "patch",
+	mdata_payload_pb2.MdPayload_UNSET_ATTRIBUTES: "unset",
+	mdata_payload_pb2.MdPayload_ORG_CREATE:       "org_create",
+	mdata_payload_pb2.MdPayload_ORG_UPDATE:       "org_update",
+	mdata_payload_pb2.MdPayload_TRANSFER:         "transfer",
+	mdata_payload_pb2.MdPayload_AGENT_CREATE:     "agent_create",
+	mdata_payload_pb2.MdPayload_AGENT_UPDATE:     "agent_update",
 }
 
 func (p MdPayload) invaildChar() (bool, string) {
@@ -56,8 +95,10 @@ func (p *MdPayload) invalidAttributes() bool {
 	return false
 }
 
-func (p *MdPayload) invalidGtin() bool {
-	// Verify the length of GTIN is 14 integers (no symbols, no letters)
+func (p *MdPayload) invalidLegacyGtin() bool {
+	// Verify the length of GTIN is 14 integers (no symbols, no letters). This
+	// is the only GTIN check family version 1.0 has ever made, so transactions
+	// on existing chains validate the same way when they are replayed.
 	_, err := strconv.Atoi(p.Gtin)
 	if err != nil {
 		// Error converting string to int; invalid
@@ -72,18 +113,9 @@ func (p *MdPayload) invalidGtin() bool {
 }
 
 func (p *MdPayload) invalidState() bool {
-	// Verify the state setting is valid: one of ACTIVE, INACTIVE, DISCONTINUED
-	validStates := []string{"ACTIVE", "INACTIVE", "DISCONTINUED"}
-	var foundMatch bool
-	for _, state := range validStates {
-		if p.State == state {
-			foundMatch = true
-		}
-		if foundMatch {
-			return false
-		}
-	}
-	return true
+	// Verify the state is well formed. The states a product can take are read
+	// from the mdata.product.states setting when the transaction is applied.
+	return !lifecycle.ValidName(p.State)
 }
 
 func FromBytes(payloadData []byte) (*MdPayload, error) {
@@ -107,42 +139,200 @@ func FromBytes(payloadData []byte) (*MdPayload, error) {
 	payload.Attributes = parts[2 : len(parts)-1]
 	payload.State = parts[len(parts)-1]
 
-	if len(payload.Action) < 1 {
-		return nil, &processor.InvalidTransactionError{Msg: "Action is required"}
+	err := payload.validate(false)
+	if err != nil {
+		return nil, err
 	}
 
-	if payload.invalidGtin() {
-		return nil, &processor.InvalidTransactionError{Msg: "Gtin-14 is required"}
+	if payload.invalidAttributes() {
+		return nil, &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Invalid attributes (attributes must be in key=value pairs): %v", payload.Attributes)}
 	}
 
-	if payload.Action == "update" {
-		if len(payload.Attributes) < 1 || payload.Attributes[0] == "" {
-			return nil, &processor.InvalidTransactionError{Msg: "Attributes are required for update"}
-		}
+	isInvalid, invalidString := payload.invaildChar()
+	if isInvalid {
+		return nil, &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Invalid Name (char '|' not allowed): '%v'", invalidString)}
 	}
 
-	if payload.invalidAttributes() {
-		return nil, &processor.InvalidTransactionError{
-			Msg: fmt.Sprintf("Invalid attributes (attributes must be in key=value pairs): %v", payload.Attributes)}
+	return &payload, nil
+}
+
+func FromProtobuf(payloadData []byte) (*MdPayload, error) {
+	if payloadData == nil {
+		return nil, &processor.InvalidTransactionError{Msg: "Must contain payload"}
 	}
 
-	if payload.Action == "set" {
+	pb := &mdata_payload_pb2.MdPayload{}
+	err := proto.Unmarshal(payloadData, pb)
+	if err != nil {
+		return nil, &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Payload is malformed: %v", err)}
+	}
 
-		if len(payload.State) < 1 {
-			return nil, &processor.InvalidTransactionError{Msg: "State is required to set"}
-		}
+	payload := MdPayload{}
+	payload.Action = actions[pb.GetAction()]
+	payload.Gtin = pb.GetGtin()
+	payload.State = pb.GetState()
+	payload.Timestamp = pb.GetTimestamp()
+	payload.RemoveKeys = pb.GetRemoveKeys()
+	payload.OrgId = pb.GetOrgId()
+	payload.OrgName = pb.GetOrgName()
+	payload.Admins = pb.GetAdmins()
+	payload.CompanyPrefixes = pb.GetCompanyPrefixes()
+	payload.NewOwner = pb.GetNewOwner()
+	payload.PublicKey = pb.GetPublicKey()
+	payload.Roles = pb.GetRoles()
+	payload.ExpectedVersion = pb.GetExpectedVersion()
+	// Clients that predate check_version only set expected_version, to a
+	// version other than 0
+	payload.CheckVersion = pb.GetCheckVersion() || pb.GetExpectedVersion() != 0
 
-		if payload.invalidState() {
+	for _, attr := range pb.GetAttributes() {
+		if attr.GetKey() == "" {
 			return nil, &processor.InvalidTransactionError{
-				Msg: fmt.Sprintf("Invalid state (state must be one of ACTIVE, INACTIVE, DISCONTINUED), GOT: %v", payload.State)}
+				Msg: fmt.Sprintf("Attribute key is required: '=%v'", attr.GetValue())}
 		}
+		payload.Attributes = append(payload.Attributes, model.AttributeEntry(attr.GetKey(), attr.GetValue()))
 	}
 
-	isInvalid, invalidString := payload.invaildChar()
-	if isInvalid {
-		return nil, &processor.InvalidTransactionError{
-			Msg: fmt.Sprintf("Invalid Name (char '|' not allowed): '%v'", invalidString)}
+	err = payload.validate(true)
+	if err != nil {
+		return nil, err
 	}
 
 	return &payload, nil
 }
+
+// validate runs the checks shared by every payload encoding. GS1 check
+// digits of created products are only verified, and shorter GTINs
+// normalized, when checkDigit is set: family version 1.0 keeps its original
+// GTIN rule.
+func (p *MdPayload) validate(checkDigit bool) error {
+	if len(p.Action) < 1 {
+		return &processor.InvalidTransactionError{Msg: "Action is required"}
+	}
+
+	if p.Action == "org_create" || p.Action == "org_update" {
+		return p.validateOrganization()
+	}
+
+	if p.Action == "agent_create" || p.Action == "agent_update" {
+		return p.validateAgent()
+	}
+
+	if checkDigit {
+		// Verify the GTIN has a valid GS1 check digit and store it as a
+		// GTIN-14, the form product addresses are derived from. Products
+		// family version 1.0 created with any 14 digits stay addressable:
+		// only a create needs the check digit.
+		normalize := gs1.NormalizeStoredGtin
+		if p.Action == "create" {
+			normalize = gs1.NormalizeGtin
+		}
+		gtin, err := normalize(p.Gtin)
+		if err != nil {
+			return &processor.InvalidTransactionError{Msg: err.Error()}
+		}
+		p.Gtin = gtin
+	} else if p.invalidLegacyGtin() {
+		return &processor.InvalidTransactionError{Msg: "Gtin-14 is required"}
+	}
+
+	if p.Action == "update" {
+		if len(p.Attributes) < 1 || p.Attributes[0] == "" {
+			return &processor.InvalidTransactionError{Msg: "Attributes are required for update"}
+		}
+	}
+
+	if p.Action == "patch" {
+		if len(p.Attributes) < 1 && len(p.RemoveKeys) < 1 {
+			return &processor.InvalidTransactionError{Msg: "Attributes to set or remove are required for patch"}
+		}
+
+		patched := model.DeserializeAttributes(p.Attributes)
+		for _, key := range p.RemoveKeys {
+			if _, ok := patched[key]; ok {
+				return &processor.InvalidTransactionError{
+					Msg: fmt.Sprintf("Attribute '%v' cannot be both set and removed", key)}
+			}
+		}
+	}
+
+	if p.Action == "unset" {
+		if len(p.RemoveKeys) < 1 {
+			return &processor.InvalidTransactionError{Msg: "Attribute keys are required for unset"}
+		}
+	}
+
+	if p.Action == "transfer" {
+		if invalidPublicKey(p.NewOwner) {
+			return &processor.InvalidTransactionError{
+				Msg: fmt.Sprintf("Invalid new owner (must be a hex encoded compressed public key): '%v'", p.NewOwner)}
+		}
+	}
+
+	if p.Action == "set" {
+
+		if len(p.State) < 1 {
+			return &processor.InvalidTransactionError{Msg: "State is required to set"}
+		}
+
+		if p.invalidState() {
+			return &processor.InvalidTransactionError{
+				Msg: fmt.Sprintf("Invalid state (state must be upper case letters, digits and underscores), GOT: %v", p.State)}
+		}
+	}
+
+	return nil
+}
+
+// validateOrganization checks the organization actions, which carry no GTIN
+func (p *MdPayload) validateOrganization() error {
+	if len(p.OrgId) < 1 {
+		return &processor.InvalidTransactionError{Msg: "Organization id is required"}
+	}
+
+	if p.Action == "org_update" && len(p.Admins) < 1 {
+		return &processor.InvalidTransactionError{Msg: "An organization requires at least one admin"}
+	}
+
+	for _, prefix := range p.CompanyPrefixes {
+		_, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil || len(prefix) < model.MinCompanyPrefixLength || len(prefix) > model.MaxCompanyPrefixLength {
+			return &processor.InvalidTransactionError{
+				Msg: fmt.Sprintf("Invalid GS1 company prefix (must be %v to %v digits): '%v'",
+					model.MinCompanyPrefixLength, model.MaxCompanyPrefixLength, prefix)}
+		}
+	}
+
+	return nil
+}
+
+// validateAgent checks the agent actions, which carry no GTIN
+func (p *MdPayload) validateAgent() error {
+	if invalidPublicKey(p.PublicKey) {
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Invalid agent (must be a hex encoded compressed public key): '%v'", p.PublicKey)}
+	}
+
+	if p.Action == "agent_create" && len(p.OrgId) < 1 {
+		return &processor.InvalidTransactionError{Msg: "Organization id is required to create an agent"}
+	}
+
+	for _, role := range p.Roles {
+		if !mdata_state.ValidRole(role) {
+			return &processor.InvalidTransactionError{
+				Msg: fmt.Sprintf("Invalid role (role must be one of %v), GOT: %v", strings.Join(mdata_state.Roles, ", "), role)}
+		}
+	}
+
+	return nil
+}
+
+// invalidPublicKey reports whether key is not a hex encoded, compressed
+// secp256k1 public key as used by Sawtooth signers
+func invalidPublicKey(key string) bool {
+	data, err := hex.DecodeString(key)
+	return err != nil || len(data) != 33
+}
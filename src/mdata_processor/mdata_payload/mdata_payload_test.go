@@ -1,12 +1,16 @@
 package mdata_payload
 
 import (
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/tross-tyson/mdata_go/src/protobuf/mdata_payload_pb2"
 	"reflect"
+	"strings"
 	"testing"
 )
 
 var sampleError = processor.InvalidTransactionError{Msg: "Sample Error"}
+var testPublicKey = "02" + strings.Repeat("ab", 32)
 
 var testPayloads = map[string]struct {
 	in         []byte
@@ -22,6 +26,8 @@ var testPayloads = map[string]struct {
 	6. Update with Attributes => Ok
 	7. Update with len(Attributes) < 1  => Err
 	8. Invalid character '|'
+	9. Invalid GTIN check digit => Ok, version 1.0 only checks for 14 digits
+	10. GTIN-12 => Err, version 1.0 only accepts GTIN-14
 	*/
 	//Input, expected return MdPayload, expected return Error
 	"nullPayload": { //Null payload => Err
@@ -64,6 +70,16 @@ var testPayloads = map[string]struct {
 		outPayload: nil,
 		outError:   &sampleError,
 	},
+	"invalidCheckDigit": { //Invalid GTIN check digit => Ok, version 1.0 only checks for 14 digits
+		in:         []byte("create,00012345600013,uom=cases,"),
+		outPayload: &MdPayload{Action: "create", Gtin: "00012345600013", Attributes: []string{"uom=cases"}},
+		outError:   nil,
+	},
+	"gtin12": { //GTIN-12 => Err, version 1.0 only accepts GTIN-14
+		in:         []byte("create,012345678905,uom=cases,"),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
 	"set": { //Set state to INACTIVE => OK
 		in:         []byte("set,00012345600012,,INACTIVE"),
 		outPayload: &MdPayload{Action: "set", Gtin: "00012345600012", State: "INACTIVE"},
@@ -116,6 +132,12 @@ func compareStructs(expected, actual MdPayload) bool {
 			if ev != av {
 				return false
 			}
+		case reflect.Uint64:
+			ev := expected_value.Uint()
+			av := actual_value.Uint()
+			if ev != av {
+				return false
+			}
 		}
 
 	}
@@ -145,3 +167,319 @@ func TestFromBytes(t *testing.T) {
 		}
 	}
 }
+
+func marshalPayload(pb *mdata_payload_pb2.MdPayload) []byte {
+	data, err := proto.Marshal(pb)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+var testProtobufPayloads = map[string]struct {
+	in         []byte
+	outPayload *MdPayload
+	outError   error
+}{
+	/* Test Cases
+	1. Null payload => Err
+	2. Undecodable bytes => Err
+	3. Missing action => Err
+	4. Create with attributes => Ok
+	5. Attribute values containing '=', ',' and '|' => Ok
+	6. Attribute key containing '=' => Ok, escaped
+	7. Attribute without key => Err
+	8. Update without attributes => Err
+	9. Set with malformed state => Err
+	10. Patch removing keys only => Ok
+	11. Patch without attributes or keys => Err
+	12. Patch setting and removing the same key => Err
+	13. Unset with keys => Ok
+	14. Unset without keys => Err
+	15. Org create with company prefixes => Ok
+	16. Org create without org id => Err
+	17. Org update without admins => Err
+	18. Org create with a malformed company prefix => Err
+	19. Transfer to a public key => Ok
+	20. Transfer to a malformed public key => Err
+	21. Agent create with roles => Ok
+	22. Agent create without org id => Err
+	23. Agent update with an unknown role => Err
+	24. Set with a state outside the default lifecycle => Ok, checked against the setting when applied
+	25. Invalid GTIN check digit => Err
+	26. GTIN-12 => Ok, normalized to GTIN-14
+	27. Update of a GTIN-14 with an invalid check digit => Ok, 1.0 may have stored it
+	28. GTIN-12 with an invalid check digit outside a create => Err
+	*/
+	"nullPayload": {
+		in:         nil,
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"malformed": {
+		in:         []byte("create,00012345600012,uom=cases,"),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"missingAction": {
+		in:         marshalPayload(&mdata_payload_pb2.MdPayload{Gtin: "00012345600012"}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"create": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:     mdata_payload_pb2.MdPayload_CREATE,
+			Gtin:       "00012345600012",
+			Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "uom", Value: "cases"}},
+			Timestamp:  1546300800,
+		}),
+		outPayload: &MdPayload{Action: "create", Gtin: "00012345600012", Attributes: []string{"uom=cases"}, Timestamp: 1546300800},
+		outError:   nil,
+	},
+	"invalidCheckDigit": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:     mdata_payload_pb2.MdPayload_CREATE,
+			Gtin:       "00012345600013",
+			Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "uom", Value: "cases"}},
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"gtin12": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:     mdata_payload_pb2.MdPayload_CREATE,
+			Gtin:       "012345678905",
+			Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "uom", Value: "cases"}},
+		}),
+		outPayload: &MdPayload{Action: "create", Gtin: "00012345678905", Attributes: []string{"uom=cases"}},
+		outError:   nil,
+	},
+	"legacyCheckDigit": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:     mdata_payload_pb2.MdPayload_UPDATE,
+			Gtin:       "00012345600013",
+			Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "uom", Value: "cases"}},
+		}),
+		outPayload: &MdPayload{Action: "update", Gtin: "00012345600013", Attributes: []string{"uom=cases"}},
+		outError:   nil,
+	},
+	"gtin12CheckDigit": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:     mdata_payload_pb2.MdPayload_UPDATE,
+			Gtin:       "012345678900",
+			Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "uom", Value: "cases"}},
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"valueWithDelimiters": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:     mdata_payload_pb2.MdPayload_UPDATE,
+			Gtin:       "00012345600012",
+			Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "formula", Value: "a=b"}, {Key: "desc", Value: "cheese, cheddar|mild"}},
+		}),
+		outPayload: &MdPayload{Action: "update", Gtin: "00012345600012", Attributes: []string{`formula=a\=b`, `desc=cheese\, cheddar\|mild`}},
+		outError:   nil,
+	},
+	"expectedVersion": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:          mdata_payload_pb2.MdPayload_UPDATE,
+			Gtin:            "00012345600012",
+			Attributes:      []*mdata_payload_pb2.MdPayload_Attribute{{Key: "uom", Value: "lbs"}},
+			ExpectedVersion: 4,
+		}),
+		outPayload: &MdPayload{Action: "update", Gtin: "00012345600012", Attributes: []string{"uom=lbs"}, ExpectedVersion: 4, CheckVersion: true},
+		outError:   nil,
+	},
+	"expectedVersionZero": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:       mdata_payload_pb2.MdPayload_UPDATE,
+			Gtin:         "00012345600012",
+			Attributes:   []*mdata_payload_pb2.MdPayload_Attribute{{Key: "uom", Value: "lbs"}},
+			CheckVersion: true,
+		}),
+		outPayload: &MdPayload{Action: "update", Gtin: "00012345600012", Attributes: []string{"uom=lbs"}, CheckVersion: true},
+		outError:   nil,
+	},
+	"keyWithEquals": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:     mdata_payload_pb2.MdPayload_UPDATE,
+			Gtin:       "00012345600012",
+			Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "a=b", Value: "c"}},
+		}),
+		outPayload: &MdPayload{Action: "update", Gtin: "00012345600012", Attributes: []string{`a\=b=c`}},
+		outError:   nil,
+	},
+	"emptyKey": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:     mdata_payload_pb2.MdPayload_UPDATE,
+			Gtin:       "00012345600012",
+			Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "", Value: "c"}},
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"noAttributesUpdate": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action: mdata_payload_pb2.MdPayload_UPDATE,
+			Gtin:   "00012345600012",
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"patchRemoveOnly": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:     mdata_payload_pb2.MdPayload_PATCH,
+			Gtin:       "00012345600012",
+			RemoveKeys: []string{"weight"},
+		}),
+		outPayload: &MdPayload{Action: "patch", Gtin: "00012345600012"},
+		outError:   nil,
+	},
+	"patchEmpty": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action: mdata_payload_pb2.MdPayload_PATCH,
+			Gtin:   "00012345600012",
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"patchSetAndRemove": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:     mdata_payload_pb2.MdPayload_PATCH,
+			Gtin:       "00012345600012",
+			Attributes: []*mdata_payload_pb2.MdPayload_Attribute{{Key: "weight", Value: "300"}},
+			RemoveKeys: []string{"weight"},
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"unset": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:     mdata_payload_pb2.MdPayload_UNSET_ATTRIBUTES,
+			Gtin:       "00012345600012",
+			RemoveKeys: []string{"weight", "uom"},
+		}),
+		outPayload: &MdPayload{Action: "unset", Gtin: "00012345600012"},
+		outError:   nil,
+	},
+	"unsetNoKeys": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action: mdata_payload_pb2.MdPayload_UNSET_ATTRIBUTES,
+			Gtin:   "00012345600012",
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"orgCreate": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:          mdata_payload_pb2.MdPayload_ORG_CREATE,
+			OrgId:           "acme",
+			OrgName:         "Acme",
+			CompanyPrefixes: []string{"0614141", "123456789012"},
+		}),
+		outPayload: &MdPayload{Action: "org_create", OrgId: "acme", OrgName: "Acme"},
+		outError:   nil,
+	},
+	"orgCreateNoId": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:          mdata_payload_pb2.MdPayload_ORG_CREATE,
+			CompanyPrefixes: []string{"0614141"},
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"orgUpdateNoAdmins": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action: mdata_payload_pb2.MdPayload_ORG_UPDATE,
+			OrgId:  "acme",
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"orgCreateBadPrefix": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:          mdata_payload_pb2.MdPayload_ORG_CREATE,
+			OrgId:           "acme",
+			CompanyPrefixes: []string{"061"},
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"transfer": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:   mdata_payload_pb2.MdPayload_TRANSFER,
+			Gtin:     "00012345600012",
+			NewOwner: testPublicKey,
+		}),
+		outPayload: &MdPayload{Action: "transfer", Gtin: "00012345600012", NewOwner: testPublicKey},
+		outError:   nil,
+	},
+	"transferBadKey": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:   mdata_payload_pb2.MdPayload_TRANSFER,
+			Gtin:     "00012345600012",
+			NewOwner: "02aa",
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"agentCreate": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:    mdata_payload_pb2.MdPayload_AGENT_CREATE,
+			OrgId:     "acme",
+			PublicKey: testPublicKey,
+			Roles:     []string{"product.create", "product.set_state"},
+		}),
+		outPayload: &MdPayload{Action: "agent_create", OrgId: "acme", PublicKey: testPublicKey},
+		outError:   nil,
+	},
+	"agentCreateNoOrg": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:    mdata_payload_pb2.MdPayload_AGENT_CREATE,
+			PublicKey: testPublicKey,
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"agentUpdateUnknownRole": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action:    mdata_payload_pb2.MdPayload_AGENT_UPDATE,
+			PublicKey: testPublicKey,
+			Roles:     []string{"product.admin"},
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"invalidState": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action: mdata_payload_pb2.MdPayload_SET,
+			Gtin:   "00012345600012",
+			State:  "retired",
+		}),
+		outPayload: nil,
+		outError:   &sampleError,
+	},
+	"configuredState": {
+		in: marshalPayload(&mdata_payload_pb2.MdPayload{
+			Action: mdata_payload_pb2.MdPayload_SET,
+			Gtin:   "00012345600012",
+			State:  "RETIRED",
+		}),
+		outPayload: &MdPayload{Action: "set", Gtin: "00012345600012", State: "RETIRED"},
+		outError:   nil,
+	},
+}
+
+func TestFromProtobuf(t *testing.T) {
+	for name, test := range testProtobufPayloads {
+		t.Logf("Running test case: %s", name)
+		payload, err := FromProtobuf(test.in)
+		if compareExpectedActualPayload(test.outPayload, payload) != true || compareExpectedActualError(test.outError, err) != true {
+			t.Errorf("Test Case Failure %v \n FromProtobuf(%v) => GOT %v, %v, WANT %v, %v", name, test.in, payload, err, test.outPayload, test.outError)
+		}
+		if test.outPayload != nil && payload != nil && !reflect.DeepEqual(test.outPayload.Attributes, payload.Attributes) {
+			t.Errorf("Test Case Failure %v \n FromProtobuf(%v) => GOT Attributes %v, WANT %v", name, test.in, payload.Attributes, test.outPayload.Attributes)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package mdata_state
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"github.com/tross-tyson/mdata_go/src/protobuf/organization_pb2"
+)
+
+// Roles an organization admin can grant to an agent. Each allows the product
+// actions named after it on the products of the agent's organization.
+const (
+	RoleCreate   = "product.create"
+	RoleUpdate   = "product.update"
+	RoleDelete   = "product.delete"
+	RoleSetState = "product.set_state"
+	RoleTransfer = "product.transfer"
+)
+
+var Roles = []string{RoleCreate, RoleUpdate, RoleDelete, RoleSetState, RoleTransfer}
+
+// ValidRole reports whether role is one of Roles
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type Agent struct {
+	PublicKey string
+	OrgId     string
+	Roles     []string
+}
+
+// HasRole reports whether the agent holds role
+func (self *Agent) HasRole(role string) bool {
+	for _, r := range self.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *MdState) GetAgent(publicKey string) (*Agent, error) {
+	agents, err := self.loadAgents(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	agent, ok := agents[publicKey]
+	if ok {
+		return agent, nil
+	}
+	return nil, nil
+}
+
+func (self *MdState) SetAgent(publicKey string, agent *Agent) error {
+	agents, err := self.loadAgents(publicKey)
+	if err != nil {
+		return err
+	}
+	agents[publicKey] = agent
+	return self.storeAgents(publicKey, agents)
+}
+
+// CheckPermission returns an InvalidTransactionError unless signer may perform
+// role on gtin. product is the product stored under gtin, nil when creating it.
+//
+// Owners of a product may perform every role on it. Otherwise the signer
+// must act for the organization owning the GTIN's company prefix, as an agent
+// holding role, or as an admin creating a product.
+// Products without owners, stored before owners were recorded, are only
+// transferred to their owner by a registrar: owning the company prefix grants
+// no rights over them.
+func (self *MdState) CheckPermission(signer string, gtin string, product *model.Product, role string) error {
+	if product != nil && product.IsOwner(signer) {
+		return nil
+	}
+
+	if product != nil && len(product.Owners) == 0 {
+		registrar, err := self.IsRegistrar(signer)
+		if err != nil {
+			return err
+		}
+		if registrar && role == RoleTransfer {
+			return nil
+		}
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Product %v has no owners, a registrar must transfer it to its owner first", gtin)}
+	}
+
+	organization, err := self.GetGtinOwner(gtin)
+	if err != nil {
+		return err
+	}
+	if organization == nil {
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("No organization owns the GS1 company prefix of GTIN %v", gtin)}
+	}
+
+	if organization.IsAdmin(signer) && product == nil {
+		return nil
+	}
+
+	agent, err := self.GetAgent(signer)
+	if err != nil {
+		return err
+	}
+	if agent != nil && agent.OrgId == organization.OrgId && agent.HasRole(role) {
+		return nil
+	}
+
+	if product != nil {
+		return &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Signer %v is not an owner of product %v and has no %v role in organization %v", signer, gtin, role, organization.OrgId)}
+	}
+	return &processor.InvalidTransactionError{
+		Msg: fmt.Sprintf("Signer %v is not an admin of organization %v, which owns GTIN %v, and has no %v role in it", signer, organization.OrgId, gtin, role)}
+}
+
+func (self *MdState) storeAgents(publicKey string, agents map[string]*Agent) error {
+	var keys []string
+	for key := range agents {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	container := &organization_pb2.AgentContainer{}
+	for _, key := range keys {
+		container.Entries = append(container.Entries, agentMessage(agents[key]))
+	}
+
+	data, err := proto.Marshal(container)
+	if err != nil {
+		return err
+	}
+	return self.storeAddress(model.AgentAddress(publicKey), data)
+}
+
+// agentMessage returns the protobuf message storing agent, its roles sorted
+// so that every node writes the same bytes
+func agentMessage(agent *Agent) *organization_pb2.Agent {
+	roles := append([]string{}, agent.Roles...)
+	sort.Strings(roles)
+
+	return &organization_pb2.Agent{
+		PublicKey: agent.PublicKey,
+		OrgId:     agent.OrgId,
+		Roles:     roles,
+	}
+}
+
+func (self *MdState) loadAgents(publicKey string) (map[string]*Agent, error) {
+	agents := make(map[string]*Agent)
+	data, err := self.loadAddress(model.AgentAddress(publicKey))
+	if err != nil || data == nil {
+		return agents, err
+	}
+
+	container := &organization_pb2.AgentContainer{}
+	err = proto.Unmarshal(data, container)
+	if err != nil {
+		return nil, &processor.InternalError{
+			Msg: fmt.Sprintf("Malformed agent data: %v", err)}
+	}
+	for _, entry := range container.GetEntries() {
+		agents[entry.GetPublicKey()] = &Agent{
+			PublicKey: entry.GetPublicKey(),
+			OrgId:     entry.GetOrgId(),
+			Roles:     entry.GetRoles(),
+		}
+	}
+	return agents, nil
+}
@@ -0,0 +1,211 @@
+package mdata_state
+
+import (
+	"testing"
+
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/lifecycle"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"github.com/tross-tyson/mdata_go/src/protobuf/organization_pb2"
+)
+
+var testAdmin string = "02aa"
+var testAgentKey string = "03cc"
+var testRegistrar string = "05ee"
+
+func agentData(agent *Agent) []byte {
+	return mustMarshal(&organization_pb2.AgentContainer{
+		Entries: []*organization_pb2.Agent{{
+			PublicKey: agent.PublicKey,
+			OrgId:     agent.OrgId,
+			Roles:     agent.Roles,
+		}},
+	})
+}
+
+func TestCheckPermission(t *testing.T) {
+
+	ownedProduct := &model.Product{Gtin: testGtin, State: testState, Creator: "04dd", Owners: []string{"04dd"}}
+	legacyProduct := &model.Product{Gtin: testGtin, State: testState}
+
+	tests := map[string]struct {
+		signer   string
+		product  *model.Product
+		role     string
+		prefixes map[string]string
+		agent    *Agent
+		err      error
+	}{
+		"owner": { //Owners need no organization
+			signer:  "04dd",
+			product: ownedProduct,
+			role:    RoleDelete,
+			err:     nil,
+		},
+		"noOrganization": {
+			signer:   testAdmin,
+			product:  nil,
+			role:     RoleCreate,
+			prefixes: map[string]string{},
+			err:      &processor.InvalidTransactionError{},
+		},
+		"adminCreates": {
+			signer:   testAdmin,
+			product:  nil,
+			role:     RoleCreate,
+			prefixes: map[string]string{"1234567": "acme"},
+			err:      nil,
+		},
+		"adminLegacyProduct": { //Owning the company prefix grants nothing over products without owners
+			signer:  testAdmin,
+			product: legacyProduct,
+			role:    RoleUpdate,
+			err:     &processor.InvalidTransactionError{},
+		},
+		"registrarTransfersLegacyProduct": {
+			signer:  testRegistrar,
+			product: legacyProduct,
+			role:    RoleTransfer,
+			err:     nil,
+		},
+		"registrarUpdatesLegacyProduct": {
+			signer:  testRegistrar,
+			product: legacyProduct,
+			role:    RoleUpdate,
+			err:     &processor.InvalidTransactionError{},
+		},
+		"adminOwnedProduct": {
+			signer:   testAdmin,
+			product:  ownedProduct,
+			role:     RoleUpdate,
+			prefixes: map[string]string{"1234567": "acme"},
+			agent:    nil,
+			err:      &processor.InvalidTransactionError{},
+		},
+		"agentWithRole": {
+			signer:   testAgentKey,
+			product:  ownedProduct,
+			role:     RoleSetState,
+			prefixes: map[string]string{"1234567": "acme"},
+			agent:    &Agent{PublicKey: testAgentKey, OrgId: "acme", Roles: []string{RoleSetState, RoleUpdate}},
+			err:      nil,
+		},
+		"agentCreates": {
+			signer:   testAgentKey,
+			product:  nil,
+			role:     RoleCreate,
+			prefixes: map[string]string{"1234567": "acme"},
+			agent:    &Agent{PublicKey: testAgentKey, OrgId: "acme", Roles: []string{RoleCreate}},
+			err:      nil,
+		},
+		"agentWithoutRole": {
+			signer:   testAgentKey,
+			product:  ownedProduct,
+			role:     RoleDelete,
+			prefixes: map[string]string{"1234567": "acme"},
+			agent:    &Agent{PublicKey: testAgentKey, OrgId: "acme", Roles: []string{RoleSetState, RoleUpdate}},
+			err:      &processor.InvalidTransactionError{},
+		},
+		"agentOfOtherOrganization": {
+			signer:   testAgentKey,
+			product:  ownedProduct,
+			role:     RoleDelete,
+			prefixes: map[string]string{"1234567": "acme"},
+			agent:    &Agent{PublicKey: testAgentKey, OrgId: "other", Roles: []string{RoleDelete}},
+			err:      &processor.InvalidTransactionError{},
+		},
+		"error": {
+			signer:   testAdmin,
+			product:  nil,
+			role:     RoleCreate,
+			prefixes: nil,
+			err:      sampleError,
+		},
+	}
+
+	organization := &Organization{OrgId: "acme", Admins: []string{testAdmin}, CompanyPrefixes: []string{"1234567"}}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+
+		testContext := &mockContext{}
+
+		if name == "error" {
+			testContext.On("GetState", model.CompanyPrefixAddresses(testGtin)).Return(nil, sampleError)
+		} else if test.prefixes != nil {
+			returnState := make(map[string][]byte)
+			for prefix, orgId := range test.prefixes {
+				returnState[model.CompanyPrefixAddress(prefix)] = prefixData(prefix, orgId)
+			}
+			testContext.On("GetState", model.CompanyPrefixAddresses(testGtin)).Return(returnState, nil)
+		}
+		if len(test.prefixes) > 0 {
+			address := model.OrganizationAddress("acme")
+			testContext.On("GetState", []string{address}).Return(
+				map[string][]byte{address: organizationData(organization)},
+				nil,
+			)
+		}
+		if test.product == legacyProduct {
+			address := lifecycle.SettingAddress(model.RegistrarsSetting)
+			testContext.On("GetState", []string{address}).Return(
+				map[string][]byte{address: settingData(model.RegistrarsSetting, testRegistrar)},
+				nil,
+			)
+		}
+		if test.signer == testAgentKey || name == "adminOwnedProduct" {
+			address := model.AgentAddress(test.signer)
+			returnState := make(map[string][]byte)
+			if test.agent != nil {
+				returnState[address] = agentData(test.agent)
+			}
+			testContext.On("GetState", []string{address}).Return(returnState, nil)
+		}
+
+		testState := &MdState{
+			context:      testContext,
+			addressCache: make(map[string][]byte),
+		}
+
+		err := testState.CheckPermission(test.signer, testGtin, test.product, test.role)
+		if test.err == nil {
+			assert.Nil(t, err)
+		} else if test.err == sampleError {
+			assert.Equal(t, sampleError, err)
+		} else {
+			assert.IsType(t, test.err, err)
+		}
+		testContext.AssertExpectations(t)
+	}
+}
+
+func TestSetAgent(t *testing.T) {
+	address := model.AgentAddress(testAgentKey)
+	agent := &Agent{PublicKey: testAgentKey, OrgId: "acme", Roles: []string{RoleUpdate, RoleCreate}}
+	sorted := &Agent{PublicKey: testAgentKey, OrgId: "acme", Roles: []string{RoleCreate, RoleUpdate}}
+
+	testContext := &mockContext{}
+	testContext.On("GetState", []string{address}).Return(nil, nil)
+	testContext.On("SetState", map[string][]byte{address: agentData(sorted)}).Return([]string{address}, nil)
+
+	testState := &MdState{
+		context:      testContext,
+		addressCache: make(map[string][]byte),
+	}
+
+	err := testState.SetAgent(testAgentKey, agent)
+	assert.Nil(t, err)
+	stored, err := testState.GetAgent(testAgentKey)
+	assert.Nil(t, err)
+	assert.Equal(t, sorted, stored)
+	testContext.AssertExpectations(t)
+}
+
+func TestValidRole(t *testing.T) {
+	for _, role := range Roles {
+		assert.True(t, ValidRole(role))
+	}
+	assert.False(t, ValidRole("product.admin"))
+	assert.False(t, ValidRole(""))
+}
@@ -0,0 +1,88 @@
+package mdata_state
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+// ProductEvents returns the types of the events describing the change from
+// previous to product, either of which is nil when the product is created or
+// deleted
+func ProductEvents(previous *model.Product, product *model.Product) []string {
+	if previous == nil {
+		return []string{model.EventProductCreated}
+	}
+	if product == nil {
+		return []string{model.EventProductDeleted}
+	}
+	if previous.State == product.State {
+		return []string{model.EventProductUpdated}
+	}
+	events := []string{model.EventStateChanged}
+	if len(DiffAttributes(previous.Attributes, product.Attributes)) > 0 || !sameOwners(previous.Owners, product.Owners) {
+		events = append(events, model.EventProductUpdated)
+	}
+	return events
+}
+
+// AddProductEvents emits the events describing the change signer made from
+// previous to product
+func (self *MdState) AddProductEvents(signer string, previous *model.Product, product *model.Product) error {
+	subject := product
+	if subject == nil {
+		subject = previous
+	}
+	data, err := model.EncodeProducts([]*model.Product{subject})
+	if err != nil {
+		return &processor.InternalError{Msg: err.Error()}
+	}
+
+	attributes := []processor.Attribute{
+		{Key: model.EventGtin, Value: subject.Gtin},
+		{Key: model.EventSigner, Value: signer},
+		{Key: model.EventState, Value: subject.State},
+	}
+	if previous != nil && product != nil && previous.State != product.State {
+		attributes = append(attributes, processor.Attribute{Key: model.EventPreviousState, Value: previous.State})
+	}
+	for _, k := range subject.Attributes.Keys() {
+		attributes = append(attributes, processor.Attribute{Key: model.EventAttributePrefix + k, Value: subject.Attributes[k]})
+	}
+
+	for _, eventType := range ProductEvents(previous, product) {
+		err = self.context.AddEvent(eventType, attributes, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddOrganizationEvent emits an event of eventType for organization, changed
+// by signer
+func (self *MdState) AddOrganizationEvent(eventType string, signer string, organization *Organization) error {
+	data, err := proto.Marshal(organizationMessage(organization))
+	if err != nil {
+		return &processor.InternalError{Msg: err.Error()}
+	}
+	attributes := []processor.Attribute{
+		{Key: model.EventOrgId, Value: organization.OrgId},
+		{Key: model.EventSigner, Value: signer},
+	}
+	return self.context.AddEvent(eventType, attributes, data)
+}
+
+// AddAgentEvent emits an event of eventType for agent, changed by signer
+func (self *MdState) AddAgentEvent(eventType string, signer string, agent *Agent) error {
+	data, err := proto.Marshal(agentMessage(agent))
+	if err != nil {
+		return &processor.InternalError{Msg: err.Error()}
+	}
+	attributes := []processor.Attribute{
+		{Key: model.EventPublicKey, Value: agent.PublicKey},
+		{Key: model.EventOrgId, Value: agent.OrgId},
+		{Key: model.EventSigner, Value: signer},
+	}
+	return self.context.AddEvent(eventType, attributes, data)
+}
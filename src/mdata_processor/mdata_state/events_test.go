@@ -0,0 +1,114 @@
+package mdata_state
+
+import (
+	"testing"
+
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+func TestProductEvents(t *testing.T) {
+	active := &model.Product{Gtin: testGtin, Attributes: model.Attributes{"uom": "cases"}, State: "ACTIVE", Owners: []string{"02aa"}}
+
+	tests := map[string]struct {
+		previous *model.Product
+		product  *model.Product
+		events   []string
+	}{
+		"create": {
+			previous: nil,
+			product:  active,
+			events:   []string{model.EventProductCreated},
+		},
+		"delete": {
+			previous: active,
+			product:  nil,
+			events:   []string{model.EventProductDeleted},
+		},
+		"patch": {
+			previous: active,
+			product:  &model.Product{Gtin: testGtin, Attributes: model.Attributes{"uom": "lbs"}, State: "ACTIVE", Owners: []string{"02aa"}},
+			events:   []string{model.EventProductUpdated},
+		},
+		"transfer": {
+			previous: active,
+			product:  &model.Product{Gtin: testGtin, Attributes: model.Attributes{"uom": "cases"}, State: "ACTIVE", Owners: []string{"03bb"}},
+			events:   []string{model.EventProductUpdated},
+		},
+		"setState": {
+			previous: active,
+			product:  &model.Product{Gtin: testGtin, Attributes: model.Attributes{"uom": "cases"}, State: "INACTIVE", Owners: []string{"02aa"}},
+			events:   []string{model.EventStateChanged},
+		},
+		"updateReactivating": {
+			previous: &model.Product{Gtin: testGtin, Attributes: model.Attributes{"uom": "cases"}, State: "DISCONTINUED", Owners: []string{"02aa"}},
+			product:  &model.Product{Gtin: testGtin, Attributes: model.Attributes{"uom": "lbs"}, State: "ACTIVE", Owners: []string{"02aa"}},
+			events:   []string{model.EventStateChanged, model.EventProductUpdated},
+		},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		assert.Equal(t, test.events, ProductEvents(test.previous, test.product))
+	}
+}
+
+func TestAddProductEvents(t *testing.T) {
+	previous := &model.Product{Gtin: testGtin, Attributes: model.Attributes{"uom": "cases", "brand": "Acme"}, State: "ACTIVE", Owners: []string{"02aa"}, Version: 1}
+	product := &model.Product{Gtin: testGtin, Attributes: model.Attributes{"uom": "cases", "brand": "Acme"}, State: "INACTIVE", Owners: []string{"02aa"}, Version: 2}
+	productData, _ := model.EncodeProducts([]*model.Product{product})
+	previousData, _ := model.EncodeProducts([]*model.Product{previous})
+
+	testContext := &mockContext{}
+	testContext.On("AddEvent", model.EventStateChanged, []processor.Attribute{
+		{Key: "gtin", Value: testGtin},
+		{Key: "signer", Value: "03bb"},
+		{Key: "state", Value: "INACTIVE"},
+		{Key: "previous_state", Value: "ACTIVE"},
+		{Key: "attr.brand", Value: "Acme"},
+		{Key: "attr.uom", Value: "cases"},
+	}, productData).Return(nil)
+	testContext.On("AddEvent", model.EventProductDeleted, []processor.Attribute{
+		{Key: "gtin", Value: testGtin},
+		{Key: "signer", Value: "02aa"},
+		{Key: "state", Value: "ACTIVE"},
+		{Key: "attr.brand", Value: "Acme"},
+		{Key: "attr.uom", Value: "cases"},
+	}, previousData).Return(nil)
+
+	testState := &MdState{
+		context:      testContext,
+		addressCache: make(map[string][]byte),
+	}
+
+	assert.Nil(t, testState.AddProductEvents("03bb", previous, product))
+	// A deleted product is described as it was before the delete
+	assert.Nil(t, testState.AddProductEvents("02aa", previous, nil))
+	testContext.AssertExpectations(t)
+}
+
+func TestAddOrganizationAndAgentEvents(t *testing.T) {
+	organization := &Organization{OrgId: "acme", Name: "Acme", Admins: []string{"02aa"}, CompanyPrefixes: []string{"1234567"}}
+	agent := &Agent{PublicKey: "03cc", OrgId: "acme", Roles: []string{RoleUpdate}}
+
+	testContext := &mockContext{}
+	testContext.On("AddEvent", model.EventOrgCreated, []processor.Attribute{
+		{Key: "org_id", Value: "acme"},
+		{Key: "signer", Value: "05ee"},
+	}, mustMarshal(organizationMessage(organization))).Return(nil)
+	testContext.On("AddEvent", model.EventAgentUpdated, []processor.Attribute{
+		{Key: "public_key", Value: "03cc"},
+		{Key: "org_id", Value: "acme"},
+		{Key: "signer", Value: "02aa"},
+	}, mustMarshal(agentMessage(agent))).Return(nil)
+
+	testState := &MdState{
+		context:      testContext,
+		addressCache: make(map[string][]byte),
+	}
+
+	assert.Nil(t, testState.AddOrganizationEvent(model.EventOrgCreated, "05ee", organization))
+	assert.Nil(t, testState.AddAgentEvent(model.EventAgentUpdated, "02aa", agent))
+	testContext.AssertExpectations(t)
+}
@@ -0,0 +1,249 @@
+package mdata_state
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"github.com/tross-tyson/mdata_go/src/protobuf/history_pb2"
+)
+
+// Kinds of AttributeChange
+const (
+	AttributeAdded   = "added"
+	AttributeChanged = "changed"
+	AttributeRemoved = "removed"
+)
+
+var attributeChangeKinds = map[string]history_pb2.AttributeChange_Kind{
+	AttributeAdded:   history_pb2.AttributeChange_ADDED,
+	AttributeChanged: history_pb2.AttributeChange_CHANGED,
+	AttributeRemoved: history_pb2.AttributeChange_REMOVED,
+}
+
+type AttributeChange struct {
+	Kind     string
+	Key      string
+	OldValue string
+	NewValue string
+}
+
+// HistoryEntry records one transaction applied to a product
+type HistoryEntry struct {
+	Version       uint64
+	Signer        string
+	Action        string
+	Timestamp     int64
+	PreviousState string
+	State         string
+	Changes       []AttributeChange
+	Owners        []string
+}
+
+// NewHistoryEntry describes the change from previous to product, either of
+// which is nil when the product is created or deleted. The caller sets the
+// version, the one the change moved the product to.
+func NewHistoryEntry(signer string, action string, timestamp int64, previous *model.Product, product *model.Product) *HistoryEntry {
+	entry := &HistoryEntry{
+		Signer:    signer,
+		Action:    action,
+		Timestamp: timestamp,
+	}
+
+	var before, after model.Attributes
+	var ownersBefore []string
+	if previous != nil {
+		entry.PreviousState = previous.State
+		before = previous.Attributes
+		ownersBefore = previous.Owners
+	}
+	if product != nil {
+		entry.State = product.State
+		after = product.Attributes
+		if !sameOwners(ownersBefore, product.Owners) {
+			entry.Owners = product.Owners
+		}
+	}
+	entry.Changes = DiffAttributes(before, after)
+	return entry
+}
+
+// DiffAttributes lists the attributes added, changed and removed going from
+// before to after, sorted by key
+func DiffAttributes(before model.Attributes, after model.Attributes) []AttributeChange {
+	var changes []AttributeChange
+	for _, k := range after.Keys() {
+		newValue := after[k]
+		oldValue, ok := before[k]
+		if !ok {
+			changes = append(changes, AttributeChange{Kind: AttributeAdded, Key: k, NewValue: newValue})
+		} else if oldValue != newValue {
+			changes = append(changes, AttributeChange{Kind: AttributeChanged, Key: k, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	for _, k := range before.Keys() {
+		if _, ok := after[k]; !ok {
+			changes = append(changes, AttributeChange{Kind: AttributeRemoved, Key: k, OldValue: before[k]})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+func sameOwners(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetHistory returns the history of gtin in version order, or nil if the
+// product was never changed
+func (self *MdState) GetHistory(gtin string) ([]*HistoryEntry, error) {
+	var history []*HistoryEntry
+	for version := uint64(1); ; version += model.HistoryPageSize {
+		histories, err := self.loadHistories(model.HistoryAddress(gtin, version))
+		if err != nil {
+			return nil, err
+		}
+		entries := histories[gtin]
+		history = append(history, entries...)
+		if len(entries) < model.HistoryPageSize {
+			return history, nil
+		}
+	}
+}
+
+// GetHistoryVersion returns the version of the last entry of the history of
+// gtin, or 0 if the product was never changed
+func (self *MdState) GetHistoryVersion(gtin string) (uint64, error) {
+	last := uint64(0)
+	for version := uint64(1); ; version += model.HistoryPageSize {
+		histories, err := self.loadHistories(model.HistoryAddress(gtin, version))
+		if err != nil {
+			return 0, err
+		}
+		entries := histories[gtin]
+		if len(entries) > 0 {
+			last = entries[len(entries)-1].Version
+		}
+		if len(entries) < model.HistoryPageSize {
+			return last, nil
+		}
+	}
+}
+
+// AppendHistory adds entry to the end of the history of gtin, on the page
+// holding its version. Versions must increase from one entry to the next.
+func (self *MdState) AppendHistory(gtin string, entry *HistoryEntry) error {
+	address := model.HistoryAddress(gtin, entry.Version)
+	histories, err := self.loadHistories(address)
+	if err != nil {
+		return err
+	}
+	entries := histories[gtin]
+	if len(entries) > 0 && entries[len(entries)-1].Version >= entry.Version {
+		return &processor.InternalError{
+			Msg: fmt.Sprintf("History of %v is at version %v, cannot append version %v", gtin, entries[len(entries)-1].Version, entry.Version)}
+	}
+	histories[gtin] = append(entries, entry)
+	return self.storeHistories(address, histories)
+}
+
+func (self *MdState) storeHistories(address string, histories map[string][]*HistoryEntry) error {
+	var gtins []string
+	for gtin := range histories {
+		gtins = append(gtins, gtin)
+	}
+	sort.Strings(gtins)
+
+	container := &history_pb2.HistoryContainer{}
+	for _, gtin := range gtins {
+		history := &history_pb2.ProductHistory{Gtin: gtin}
+		for _, entry := range histories[gtin] {
+			owners := append([]string{}, entry.Owners...)
+			sort.Strings(owners)
+			pbEntry := &history_pb2.HistoryEntry{
+				Version:       entry.Version,
+				Signer:        entry.Signer,
+				Action:        entry.Action,
+				Timestamp:     entry.Timestamp,
+				PreviousState: entry.PreviousState,
+				State:         entry.State,
+				Owners:        owners,
+			}
+			for _, change := range entry.Changes {
+				pbEntry.Changes = append(pbEntry.Changes, &history_pb2.AttributeChange{
+					Kind:     attributeChangeKinds[change.Kind],
+					Key:      change.Key,
+					OldValue: change.OldValue,
+					NewValue: change.NewValue,
+				})
+			}
+			history.Entries = append(history.Entries, pbEntry)
+		}
+		container.Entries = append(container.Entries, history)
+	}
+
+	data, err := proto.Marshal(container)
+	if err != nil {
+		return err
+	}
+	return self.storeAddress(address, data)
+}
+
+func (self *MdState) loadHistories(address string) (map[string][]*HistoryEntry, error) {
+	histories := make(map[string][]*HistoryEntry)
+	data, err := self.loadAddress(address)
+	if err != nil || data == nil {
+		return histories, err
+	}
+
+	container := &history_pb2.HistoryContainer{}
+	err = proto.Unmarshal(data, container)
+	if err != nil {
+		return nil, &processor.InternalError{
+			Msg: fmt.Sprintf("Malformed history data: %v", err)}
+	}
+
+	kinds := make(map[history_pb2.AttributeChange_Kind]string)
+	for kind, pbKind := range attributeChangeKinds {
+		kinds[pbKind] = kind
+	}
+	for _, history := range container.GetEntries() {
+		var entries []*HistoryEntry
+		for _, pbEntry := range history.GetEntries() {
+			entry := &HistoryEntry{
+				Version:       pbEntry.GetVersion(),
+				Signer:        pbEntry.GetSigner(),
+				Action:        pbEntry.GetAction(),
+				Timestamp:     pbEntry.GetTimestamp(),
+				PreviousState: pbEntry.GetPreviousState(),
+				State:         pbEntry.GetState(),
+				Owners:        pbEntry.GetOwners(),
+			}
+			for _, change := range pbEntry.GetChanges() {
+				entry.Changes = append(entry.Changes, AttributeChange{
+					Kind:     kinds[change.GetKind()],
+					Key:      change.GetKey(),
+					OldValue: change.GetOldValue(),
+					NewValue: change.GetNewValue(),
+				})
+			}
+			entries = append(entries, entry)
+		}
+		histories[history.GetGtin()] = entries
+	}
+	return histories, nil
+}
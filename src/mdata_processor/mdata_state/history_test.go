@@ -0,0 +1,127 @@
+package mdata_state
+
+import (
+	"testing"
+
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+func TestDiffAttributes(t *testing.T) {
+
+	tests := map[string]struct {
+		before  model.Attributes
+		after   model.Attributes
+		changes []AttributeChange
+	}{
+		"create": {
+			before: nil,
+			after:  model.Attributes{"uom": "cases", "brand": "Acme"},
+			changes: []AttributeChange{
+				{Kind: AttributeAdded, Key: "brand", NewValue: "Acme"},
+				{Kind: AttributeAdded, Key: "uom", NewValue: "cases"},
+			},
+		},
+		"delete": {
+			before:  model.Attributes{"uom": "cases"},
+			after:   nil,
+			changes: []AttributeChange{{Kind: AttributeRemoved, Key: "uom", OldValue: "cases"}},
+		},
+		"mixed": {
+			before: model.Attributes{"uom": "cases", "weight": "300", "brand": "Acme"},
+			after:  model.Attributes{"uom": "lbs", "brand": "Acme", "color": "red"},
+			changes: []AttributeChange{
+				{Kind: AttributeAdded, Key: "color", NewValue: "red"},
+				{Kind: AttributeChanged, Key: "uom", OldValue: "cases", NewValue: "lbs"},
+				{Kind: AttributeRemoved, Key: "weight", OldValue: "300"},
+			},
+		},
+		"unchanged": {
+			before:  model.Attributes{"uom": "cases"},
+			after:   model.Attributes{"uom": "cases"},
+			changes: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		assert.Equal(t, test.changes, DiffAttributes(test.before, test.after))
+	}
+}
+
+func TestNewHistoryEntry(t *testing.T) {
+	previous := &model.Product{Gtin: testGtin, Attributes: model.Attributes{"uom": "cases"}, State: "ACTIVE", Owners: []string{"02aa"}}
+
+	transferred := &model.Product{Gtin: testGtin, Attributes: model.Attributes{"uom": "cases"}, State: "ACTIVE", Owners: []string{"03bb"}}
+	entry := NewHistoryEntry("02aa", "transfer", 1546300800, previous, transferred)
+	assert.Equal(t, &HistoryEntry{
+		Signer:        "02aa",
+		Action:        "transfer",
+		Timestamp:     1546300800,
+		PreviousState: "ACTIVE",
+		State:         "ACTIVE",
+		Owners:        []string{"03bb"},
+	}, entry)
+
+	entry = NewHistoryEntry("02aa", "delete", 1546300800, previous, nil)
+	assert.Equal(t, "ACTIVE", entry.PreviousState)
+	assert.Equal(t, "", entry.State)
+	assert.Nil(t, entry.Owners)
+	assert.Len(t, entry.Changes, 1)
+}
+
+func TestAppendHistory(t *testing.T) {
+	state := make(map[string][]byte)
+	testState := NewMdState(newMemoryContext(state))
+
+	first := &HistoryEntry{Version: 1, Signer: "02aa", Action: "create", Timestamp: 1, State: "ACTIVE",
+		Changes: []AttributeChange{{Kind: AttributeAdded, Key: "uom", NewValue: "cases"}}, Owners: []string{"02aa"}}
+	second := &HistoryEntry{Version: 2, Signer: "02aa", Action: "set", Timestamp: 2, PreviousState: "ACTIVE", State: "INACTIVE"}
+	assert.Nil(t, testState.AppendHistory(testGtin, first))
+	assert.Nil(t, testState.AppendHistory(testGtin, second))
+	assert.IsType(t, &processor.InternalError{}, testState.AppendHistory(testGtin, &HistoryEntry{Version: 2}))
+
+	history, err := testState.GetHistory(testGtin)
+	assert.Nil(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, uint64(1), history[0].Version)
+	assert.Equal(t, uint64(2), history[1].Version)
+	assert.Equal(t, first.Changes, history[0].Changes)
+	assert.Equal(t, first.Owners, history[0].Owners)
+	assert.Equal(t, "INACTIVE", history[1].State)
+
+	other, err := testState.GetHistory("00012345600012")
+	assert.Nil(t, err)
+	assert.Nil(t, other)
+}
+
+func TestHistoryPages(t *testing.T) {
+	state := make(map[string][]byte)
+	testState := NewMdState(newMemoryContext(state))
+
+	last := uint64(2*model.HistoryPageSize + 1)
+	for version := uint64(1); version <= last; version++ {
+		assert.Nil(t, testState.AppendHistory(testGtin, &HistoryEntry{Version: version, Action: "patch"}))
+	}
+
+	// Each page holds HistoryPageSize entries
+	assert.Len(t, state, 3)
+	for _, version := range []uint64{1, model.HistoryPageSize + 1, last} {
+		assert.Contains(t, state, model.HistoryAddress(testGtin, version))
+	}
+
+	history, err := NewMdState(newMemoryContext(state)).GetHistory(testGtin)
+	assert.Nil(t, err)
+	assert.Len(t, history, int(last))
+	for i, entry := range history {
+		assert.Equal(t, uint64(i+1), entry.Version)
+	}
+
+	version, err := NewMdState(newMemoryContext(state)).GetHistoryVersion(testGtin)
+	assert.Nil(t, err)
+	assert.Equal(t, last, version)
+	version, err = testState.GetHistoryVersion("00012345600012")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), version)
+}
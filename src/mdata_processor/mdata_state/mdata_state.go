@@ -18,60 +18,15 @@
 package mdata_state
 
 import (
-	"bytes"
-	"crypto/sha512"
-	"encoding/hex"
-	"fmt"
-	"sort"
-	"strings"
-
 	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/tross-tyson/mdata_go/src/model"
 )
 
 type context interface {
 	GetState([]string) (map[string][]byte, error)
 	DeleteState([]string) ([]string, error)
 	SetState(map[string][]byte) ([]string, error)
-}
-
-/* Namespace prefix is six hex characters, or three bytes
-All data under a namespace prefix follows a consistent address and data encoding/serialization schem that is determined
-by the transaction family which defines the namespace
-*/
-var Namespace = hexdigest("mdata")[:6]
-
-type Attributes map[string]interface{}
-
-func (self Attributes) serialize() []byte {
-	var b bytes.Buffer
-	var i int = 0
-	for k, v := range self {
-		b.WriteString(fmt.Sprintf("%v=%v", k, v))
-		i += 1
-		if i < len(self) {
-			b.WriteString(",")
-		}
-	}
-	return b.Bytes()
-}
-
-func DeserializeAttributes(a []string) Attributes {
-	A := Attributes{}
-	for _, str := range a {
-		if str != "" {
-			parts := strings.Split(str, "=")
-			k, v := parts[0], parts[1]
-			A[k] = v
-		}
-	}
-
-	return A
-}
-
-type Product struct {
-	Gtin       string
-	Attributes Attributes
-	State      string
+	AddEvent(string, []processor.Attribute, []byte) error
 }
 
 // MdState handles addressing, serialization, deserialization,
@@ -81,7 +36,9 @@ type MdState struct {
 	addressCache map[string][]byte
 }
 
-func NewMdState(context *processor.Context) *MdState {
+// NewMdState reads and writes state through context, a *processor.Context
+// when applying a transaction, or a mock in tests
+func NewMdState(context context) *MdState {
 	return &MdState{
 		context:      context,
 		addressCache: make(map[string][]byte),
@@ -89,7 +46,7 @@ func NewMdState(context *processor.Context) *MdState {
 }
 
 // Define states to store
-func (self *MdState) GetProduct(gtin string) (*Product, error) {
+func (self *MdState) GetProduct(gtin string) (*model.Product, error) {
 	products, err := self.loadProducts(gtin)
 	if err != nil {
 		return nil, err
@@ -101,7 +58,7 @@ func (self *MdState) GetProduct(gtin string) (*Product, error) {
 	return nil, nil
 }
 
-func (self *MdState) SetProduct(gtin string, product *Product) error {
+func (self *MdState) SetProduct(gtin string, product *model.Product) error {
 	products, err := self.loadProducts(gtin)
 	if err != nil {
 		return err
@@ -125,106 +82,98 @@ func (self *MdState) DeleteProduct(gtin string) error {
 	}
 }
 
-func (self *MdState) storeProducts(gtin string, products map[string]*Product) error {
-	address := makeAddress(gtin)
-
-	var gtins []string
-
-	//for each Gtin (key) in map[string]*Product
-	for gtin := range products {
-		//append gtin to gtins slice of string
-		gtins = append(gtins, gtin)
+func (self *MdState) storeProducts(gtin string, products map[string]*model.Product) error {
+	var p []*model.Product
+	for _, product := range products {
+		p = append(p, product)
 	}
-	sort.Strings(gtins)
 
-	var p []*Product
-	for _, gtin := range gtins {
-		p = append(p, products[gtin])
+	data, err := model.EncodeProducts(p)
+	if err != nil {
+		return err
 	}
 
-	data := serialize(p)
-
-	self.addressCache[address] = data
-
-	_, err := self.context.SetState(map[string][]byte{
-		address: data,
-	})
-	return err
+	return self.storeAddress(model.ProductAddress(gtin), data)
 }
 
-func (self *MdState) loadProducts(gtin string) (map[string]*Product, error) {
-	address := makeAddress(gtin)
-	data, ok := self.addressCache[address]
-	if ok {
-		if self.addressCache[address] != nil {
-			return deserialize(data)
-		}
-		return make(map[string]*Product), nil
-	}
-	results, err := self.context.GetState([]string{address})
+func (self *MdState) loadProducts(gtin string) (map[string]*model.Product, error) {
+	data, err := self.loadAddress(model.ProductAddress(gtin))
 	if err != nil {
 		return nil, err
 	}
-	if len(string(results[address])) > 0 {
-		self.addressCache[address] = results[address]
-		return deserialize(results[address])
+	if data != nil {
+		return deserialize(data)
 	}
-	self.addressCache[address] = nil
-	products := make(map[string]*Product)
-	return products, nil
+	return make(map[string]*model.Product), nil
 }
 
 func (self *MdState) deleteProducts(gtin string) error {
-	address := makeAddress(gtin)
+	return self.deleteAddress(model.ProductAddress(gtin))
+}
 
-	_, err := self.context.DeleteState([]string{address})
-	return err
+// loadAddress returns the data stored at address, or nil if there is none,
+// reading through the addressCache.
+func (self *MdState) loadAddress(address string) ([]byte, error) {
+	err := self.loadAddresses([]string{address})
+	if err != nil {
+		return nil, err
+	}
+	return self.addressCache[address], nil
 }
 
-func deserialize(data []byte) (map[string]*Product, error) {
-	products := make(map[string]*Product)
-	for _, str := range strings.Split(string(data), "|") {
-		parts := strings.Split(string(str), ",")
-		if len(parts) < 3 { //Product must have at least three serialized attributes (even if Product.Attributes is empty)
-			return nil, &processor.InternalError{
-				Msg: fmt.Sprintf("Malformed product data: '%v'", string(data))}
+// loadAddresses reads every address missing from the addressCache with a
+// single GetState request.
+func (self *MdState) loadAddresses(addresses []string) error {
+	var missing []string
+	for _, address := range addresses {
+		if _, ok := self.addressCache[address]; !ok {
+			missing = append(missing, address)
 		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
 
-		attrs := parts[1 : len(parts)-1]
-
-		product := &Product{
-			Gtin:       parts[0],
-			Attributes: DeserializeAttributes(attrs),
-			State:      parts[len(parts)-1],
+	results, err := self.context.GetState(missing)
+	if err != nil {
+		return err
+	}
+	for _, address := range missing {
+		if len(string(results[address])) > 0 {
+			self.addressCache[address] = results[address]
+		} else {
+			self.addressCache[address] = nil
 		}
-		products[parts[0]] = product
 	}
-	return products, nil
+	return nil
 }
 
-func serialize(products []*Product) []byte {
-	var buffer bytes.Buffer
-	for i, product := range products {
-		//00001234567890,uom=cases,weight=200,ACTIVE|
-		buffer.WriteString(product.Gtin)
-		buffer.WriteString(",")
-		buffer.WriteString(string(product.Attributes.serialize()))
-		buffer.WriteString(",")
-		buffer.WriteString(product.State)
-		if i+1 != len(products) {
-			buffer.WriteString("|")
-		}
-	}
-	return buffer.Bytes()
+func (self *MdState) storeAddress(address string, data []byte) error {
+	self.addressCache[address] = data
+
+	_, err := self.context.SetState(map[string][]byte{
+		address: data,
+	})
+	return err
 }
 
-func makeAddress(gtin string) string {
-	return Namespace + hexdigest(gtin)[:64]
+func (self *MdState) deleteAddress(address string) error {
+	self.addressCache[address] = nil
+
+	_, err := self.context.DeleteState([]string{address})
+	return err
 }
 
-func hexdigest(str string) string {
-	hash := sha512.New()
-	hash.Write([]byte(str))
-	hashBytes := hash.Sum(nil)
-	return strings.ToLower(hex.EncodeToString(hashBytes))
+// deserialize reads the products stored at an address by GTIN
+func deserialize(data []byte) (map[string]*model.Product, error) {
+	decoded, err := model.DecodeProducts(data)
+	if err != nil {
+		return nil, &processor.InternalError{Msg: err.Error()}
+	}
+
+	products := make(map[string]*model.Product)
+	for _, product := range decoded {
+		products[product.Gtin] = product
+	}
+	return products, nil
 }
@@ -3,33 +3,45 @@ package mdata_state
 import (
 	"errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/model"
 	"testing"
 )
 
 var testGtin string = "01234567891234"
-var testAttributes Attributes = Attributes{"uom": "cases"}
-var testSetNewAttributes Attributes = Attributes{"uom": "lbs", "weight": "300"}
+var testAttributes model.Attributes = model.Attributes{"uom": "cases"}
+var testSetNewAttributes model.Attributes = model.Attributes{"uom": "lbs", "weight": "300"}
 var testState string = "ACTIVE"
-var testGtinAddress string = makeAddress(testGtin)
+var testGtinAddress string = model.ProductAddress(testGtin)
 var toDeleteGtin string = "555555555555"
-var toDeleteGtinAddress string = makeAddress(toDeleteGtin)
-var testProduct Product = Product{
+var toDeleteGtinAddress string = model.ProductAddress(toDeleteGtin)
+var testProduct model.Product = model.Product{
 	Gtin:       testGtin,
 	Attributes: testAttributes,
 	State:      testState,
 }
-var testSetNewProduct Product = Product{
+var testSetNewProduct model.Product = model.Product{
 	Gtin:       testGtin,
 	Attributes: testSetNewAttributes,
 	State:      testState,
 }
 var sampleError = errors.New("sample")
 
+// Product state as written by processors before the ProductContainer encoding
+var testLegacyData []byte = []byte(testGtin + ",uom=cases," + testState)
+
+func mustSerialize(products []*model.Product) []byte {
+	data, err := model.EncodeProducts(products)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
 func TestGetProduct(t *testing.T) {
 
 	tests := map[string]struct {
 		gtin       string
-		outProduct *Product
+		outProduct *model.Product
 		err        error
 	}{
 		"error": {
@@ -47,6 +59,11 @@ func TestGetProduct(t *testing.T) {
 			outProduct: &testProduct,
 			err:        nil,
 		},
+		"legacyProduct": {
+			gtin:       testGtin,
+			outProduct: &testProduct,
+			err:        nil,
+		},
 	}
 
 	for name, test := range tests {
@@ -56,15 +73,21 @@ func TestGetProduct(t *testing.T) {
 
 		if name == "existingProduct" {
 			returnState := make(map[string][]byte)
-			testProductSlice := make([]*Product, 1)
+			testProductSlice := make([]*model.Product, 1)
 			testProductSlice[0] = &testProduct
 
-			returnState[testGtinAddress] = serialize(testProductSlice)
+			returnState[testGtinAddress] = mustSerialize(testProductSlice)
 			testContext.On("GetState", []string{testGtinAddress}).Return(
 				returnState,
 				nil,
 			)
 		}
+		if name == "legacyProduct" {
+			testContext.On("GetState", []string{testGtinAddress}).Return(
+				map[string][]byte{testGtinAddress: testLegacyData},
+				nil,
+			)
+		}
 		if name == "emptyProduct" {
 			testContext.On("GetState", []string{testGtinAddress}).Return(
 				nil,
@@ -96,7 +119,7 @@ func TestSetProduct(t *testing.T) {
 
 	tests := map[string]struct {
 		gtin      string
-		inProduct *Product
+		inProduct *model.Product
 		err       error
 	}{
 		"newProduct": {
@@ -109,13 +132,18 @@ func TestSetProduct(t *testing.T) {
 			inProduct: &testSetNewProduct,
 			err:       nil,
 		},
+		"migrateLegacyState": { //Legacy state is rewritten as a ProductContainer
+			gtin:      testGtin,
+			inProduct: &testSetNewProduct,
+			err:       nil,
+		},
 	}
 
 	for name, test := range tests {
 		t.Logf("Running test case: %s", name)
 
 		testContext := &mockContext{}
-		testProductSlice := []*Product{&testProduct}
+		testProductSlice := []*model.Product{&testProduct}
 
 		if name == "newProduct" {
 			returnState := make(map[string][]byte)
@@ -124,7 +152,7 @@ func TestSetProduct(t *testing.T) {
 				nil,
 			)
 
-			data := serialize(testProductSlice)
+			data := mustSerialize(testProductSlice)
 			testContext.On("SetState", map[string][]byte{testGtinAddress: data}).Return(
 				[]string{testGtinAddress},
 				nil,
@@ -133,13 +161,13 @@ func TestSetProduct(t *testing.T) {
 
 		if name == "updateProductState" {
 			returnState := make(map[string][]byte)
-			returnState[testGtinAddress] = serialize(testProductSlice)
+			returnState[testGtinAddress] = mustSerialize(testProductSlice)
 			testContext.On("GetState", []string{testGtinAddress}).Return(
 				returnState,
 				nil,
 			)
 
-			data := serialize([]*Product{&testSetNewProduct})
+			data := mustSerialize([]*model.Product{&testSetNewProduct})
 			testContext.On("SetState", map[string][]byte{testGtinAddress: data}).Return(
 				[]string{testGtinAddress},
 				nil,
@@ -147,6 +175,19 @@ func TestSetProduct(t *testing.T) {
 
 		}
 
+		if name == "migrateLegacyState" {
+			testContext.On("GetState", []string{testGtinAddress}).Return(
+				map[string][]byte{testGtinAddress: testLegacyData},
+				nil,
+			)
+
+			data := mustSerialize([]*model.Product{&testSetNewProduct})
+			testContext.On("SetState", map[string][]byte{testGtinAddress: data}).Return(
+				[]string{testGtinAddress},
+				nil,
+			)
+		}
+
 		testState := &MdState{
 			context:      testContext,
 			addressCache: make(map[string][]byte),
@@ -180,12 +221,12 @@ func TestDeleteProduct(t *testing.T) {
 
 		testContext := &mockContext{}
 
-		testProductSlice := make([]*Product, 2)
+		testProductSlice := make([]*model.Product, 2)
 		testProductSlice[0] = &testProduct
 
-		testProduct2 := Product{
+		testProduct2 := model.Product{
 			Gtin:       toDeleteGtin,
-			Attributes: Attributes{"uom": "cases"},
+			Attributes: model.Attributes{"uom": "cases"},
 			State:      "INACTIVE",
 		}
 		testProductSlice[1] = &testProduct2
@@ -205,13 +246,13 @@ func TestDeleteProduct(t *testing.T) {
 
 		if name == "storeProductsWithoutDeleted" {
 			returnState := make(map[string][]byte)
-			returnState[toDeleteGtinAddress] = serialize(testProductSlice)
+			returnState[toDeleteGtinAddress] = mustSerialize(testProductSlice)
 			testContext.On("GetState", []string{toDeleteGtinAddress}).Return(
 				returnState,
 				nil,
 			)
 
-			data := serialize([]*Product{&testProduct})
+			data := mustSerialize([]*model.Product{&testProduct})
 			testContext.On("SetState", map[string][]byte{toDeleteGtinAddress: data}).Return(
 				[]string{toDeleteGtinAddress},
 				nil,
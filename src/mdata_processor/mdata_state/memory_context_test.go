@@ -0,0 +1,46 @@
+package mdata_state
+
+import mock "github.com/stretchr/testify/mock"
+
+// newMemoryContext returns a mockContext that reads and writes state, as a
+// validator would, so tests can apply several transactions in turn. Events
+// are accepted and recorded in the mock's Calls.
+func newMemoryContext(state map[string][]byte) *mockContext {
+	context := &mockContext{}
+	context.On("GetState", mock.Anything).Return(func(addresses []string) map[string][]byte {
+		results := make(map[string][]byte)
+		for _, address := range addresses {
+			if data, ok := state[address]; ok {
+				results[address] = data
+			}
+		}
+		return results
+	}, nil)
+	context.On("SetState", mock.Anything).Return(func(values map[string][]byte) []string {
+		var addresses []string
+		for address, data := range values {
+			state[address] = data
+			addresses = append(addresses, address)
+		}
+		return addresses
+	}, nil)
+	context.On("DeleteState", mock.Anything).Return(func(addresses []string) []string {
+		for _, address := range addresses {
+			delete(state, address)
+		}
+		return addresses
+	}, nil)
+	context.On("AddEvent", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return context
+}
+
+// eventTypes returns the types of the events added to context, in order
+func (self *mockContext) eventTypes() []string {
+	var eventTypes []string
+	for _, call := range self.Calls {
+		if call.Method == "AddEvent" {
+			eventTypes = append(eventTypes, call.Arguments.String(0))
+		}
+	}
+	return eventTypes
+}
@@ -3,12 +3,27 @@
 package mdata_state
 
 import mock "github.com/stretchr/testify/mock"
+import processor "github.com/hyperledger/sawtooth-sdk-go/processor"
 
 // mockContext is an autogenerated mock type for the context type
 type mockContext struct {
 	mock.Mock
 }
 
+// AddEvent provides a mock function with given fields: _a0, _a1, _a2
+func (_m *mockContext) AddEvent(_a0 string, _a1 []processor.Attribute, _a2 []byte) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []processor.Attribute, []byte) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteState provides a mock function with given fields: _a0
 func (_m *mockContext) DeleteState(_a0 []string) ([]string, error) {
 	ret := _m.Called(_a0)
@@ -0,0 +1,366 @@
+package mdata_state
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"github.com/tross-tyson/mdata_go/src/protobuf/organization_pb2"
+)
+
+type Organization struct {
+	OrgId           string
+	Name            string
+	Admins          []string
+	CompanyPrefixes []string
+}
+
+// IsAdmin reports whether publicKey is one of the organization's admins
+func (self *Organization) IsAdmin(publicKey string) bool {
+	for _, admin := range self.Admins {
+		if admin == publicKey {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *MdState) GetOrganization(orgId string) (*Organization, error) {
+	organizations, err := self.loadOrganizations(orgId)
+	if err != nil {
+		return nil, err
+	}
+	organization, ok := organizations[orgId]
+	if ok {
+		return organization, nil
+	}
+	return nil, nil
+}
+
+func (self *MdState) SetOrganization(orgId string, organization *Organization) error {
+	organizations, err := self.loadOrganizations(orgId)
+	if err != nil {
+		return err
+	}
+	organizations[orgId] = organization
+	return self.storeOrganizations(orgId, organizations)
+}
+
+// companyPrefix is the record stored for a GS1 company prefix
+type companyPrefix struct {
+	// Organization owning the prefix, "" when no organization does
+	orgId string
+	// Owned prefixes that are longer and start with the prefix
+	extensions []string
+}
+
+// GetCompanyPrefixOwner returns the id of the organization owning prefix, or
+// "" if no organization owns it.
+func (self *MdState) GetCompanyPrefixOwner(prefix string) (string, error) {
+	prefixes, err := self.loadCompanyPrefixes(prefix)
+	if err != nil {
+		return "", err
+	}
+	if record, ok := prefixes[prefix]; ok {
+		return record.orgId, nil
+	}
+	return "", nil
+}
+
+// SetCompanyPrefixOwner makes orgId the owner of prefix, and records prefix
+// as an extension of each shorter prefix it starts with
+func (self *MdState) SetCompanyPrefixOwner(prefix string, orgId string) error {
+	err := self.loadAddresses(companyPrefixAddresses(append(shorterPrefixes(prefix), prefix)))
+	if err != nil {
+		return err
+	}
+	err = self.updateCompanyPrefix(prefix, func(record *companyPrefix) {
+		record.orgId = orgId
+	})
+	if err != nil {
+		return err
+	}
+	for _, shorter := range shorterPrefixes(prefix) {
+		err = self.updateCompanyPrefix(shorter, func(record *companyPrefix) {
+			record.extensions = addPrefix(record.extensions, prefix)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteCompanyPrefix removes the owner of prefix and its extension records,
+// handling hash collisions
+func (self *MdState) DeleteCompanyPrefix(prefix string) error {
+	err := self.loadAddresses(companyPrefixAddresses(append(shorterPrefixes(prefix), prefix)))
+	if err != nil {
+		return err
+	}
+	err = self.updateCompanyPrefix(prefix, func(record *companyPrefix) {
+		record.orgId = ""
+	})
+	if err != nil {
+		return err
+	}
+	for _, shorter := range shorterPrefixes(prefix) {
+		err = self.updateCompanyPrefix(shorter, func(record *companyPrefix) {
+			record.extensions = removePrefix(record.extensions, prefix)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetOverlappingPrefix returns a company prefix owned by an organization other
+// than orgId that prefix starts with or that starts with prefix, and the id
+// of its owner, or "" and "" if there is none. Owning an overlapping prefix
+// would hand the GTINs under the longer prefix to its owner.
+func (self *MdState) GetOverlappingPrefix(prefix string, orgId string) (string, string, error) {
+	candidates := append(shorterPrefixes(prefix), prefix)
+	err := self.loadAddresses(companyPrefixAddresses(candidates))
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, candidate := range candidates {
+		owner, err := self.GetCompanyPrefixOwner(candidate)
+		if err != nil {
+			return "", "", err
+		}
+		if owner != "" && owner != orgId {
+			return candidate, owner, nil
+		}
+	}
+
+	prefixes, err := self.loadCompanyPrefixes(prefix)
+	if err != nil {
+		return "", "", err
+	}
+	if record, ok := prefixes[prefix]; ok {
+		for _, extension := range record.extensions {
+			owner, err := self.GetCompanyPrefixOwner(extension)
+			if err != nil {
+				return "", "", err
+			}
+			if owner != "" && owner != orgId {
+				return extension, owner, nil
+			}
+		}
+	}
+	return "", "", nil
+}
+
+// updateCompanyPrefix applies update to the record of prefix, deleting the
+// record once it holds neither an owner nor extensions
+func (self *MdState) updateCompanyPrefix(prefix string, update func(*companyPrefix)) error {
+	prefixes, err := self.loadCompanyPrefixes(prefix)
+	if err != nil {
+		return err
+	}
+	record, ok := prefixes[prefix]
+	if !ok {
+		record = &companyPrefix{}
+	}
+	update(record)
+	if record.orgId == "" && len(record.extensions) == 0 {
+		if !ok {
+			return nil
+		}
+		delete(prefixes, prefix)
+	} else {
+		prefixes[prefix] = record
+	}
+	if len(prefixes) > 0 {
+		return self.storeCompanyPrefixes(prefix, prefixes)
+	}
+	return self.deleteAddress(model.CompanyPrefixAddress(prefix))
+}
+
+// shorterPrefixes returns the company prefixes prefix starts with, shortest
+// first
+func shorterPrefixes(prefix string) []string {
+	var shorter []string
+	for length := model.MinCompanyPrefixLength; length < len(prefix); length++ {
+		shorter = append(shorter, prefix[:length])
+	}
+	return shorter
+}
+
+func addPrefix(prefixes []string, prefix string) []string {
+	for _, p := range prefixes {
+		if p == prefix {
+			return prefixes
+		}
+	}
+	prefixes = append(append([]string{}, prefixes...), prefix)
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+func removePrefix(prefixes []string, prefix string) []string {
+	var kept []string
+	for _, p := range prefixes {
+		if p != prefix {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// GetGtinOwner returns the organization owning the GS1 company prefix of a
+// GTIN-14, or nil if none does. Only one organization owns the prefixes of a
+// GTIN, which the longest owned prefix decides.
+func (self *MdState) GetGtinOwner(gtin string) (*Organization, error) {
+	orgId, err := self.GetGtinOwnerId(gtin)
+	if err != nil || orgId == "" {
+		return nil, err
+	}
+	return self.GetOrganization(orgId)
+}
+
+// GetGtinOwnerId returns the id of the organization owning the GS1 company
+// prefix of a GTIN-14, or "" if none does, reading only the company prefix
+// records
+func (self *MdState) GetGtinOwnerId(gtin string) (string, error) {
+	candidates := model.CompanyPrefixCandidates(gtin)
+	err := self.loadAddresses(model.CompanyPrefixAddresses(gtin))
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(candidates) - 1; i >= 0; i-- {
+		orgId, err := self.GetCompanyPrefixOwner(candidates[i])
+		if err != nil || orgId != "" {
+			return orgId, err
+		}
+	}
+	return "", nil
+}
+
+// IsGtinAdmin reports whether signer is an admin of the organization owning
+// the GS1 company prefix of gtin
+func (self *MdState) IsGtinAdmin(signer string, gtin string) (bool, error) {
+	organization, err := self.GetGtinOwner(gtin)
+	if err != nil || organization == nil {
+		return false, err
+	}
+	return organization.IsAdmin(signer), nil
+}
+
+func companyPrefixAddresses(prefixes []string) []string {
+	var addresses []string
+	for _, prefix := range prefixes {
+		addresses = append(addresses, model.CompanyPrefixAddress(prefix))
+	}
+	return addresses
+}
+
+func (self *MdState) storeOrganizations(orgId string, organizations map[string]*Organization) error {
+	var orgIds []string
+	for orgId := range organizations {
+		orgIds = append(orgIds, orgId)
+	}
+	sort.Strings(orgIds)
+
+	container := &organization_pb2.OrganizationContainer{}
+	for _, orgId := range orgIds {
+		container.Entries = append(container.Entries, organizationMessage(organizations[orgId]))
+	}
+
+	data, err := proto.Marshal(container)
+	if err != nil {
+		return err
+	}
+	return self.storeAddress(model.OrganizationAddress(orgId), data)
+}
+
+// organizationMessage returns the protobuf message storing organization, its
+// lists sorted so that every node writes the same bytes
+func organizationMessage(organization *Organization) *organization_pb2.Organization {
+	admins := append([]string{}, organization.Admins...)
+	sort.Strings(admins)
+	prefixes := append([]string{}, organization.CompanyPrefixes...)
+	sort.Strings(prefixes)
+
+	return &organization_pb2.Organization{
+		OrgId:           organization.OrgId,
+		Name:            organization.Name,
+		Admins:          admins,
+		CompanyPrefixes: prefixes,
+	}
+}
+
+func (self *MdState) loadOrganizations(orgId string) (map[string]*Organization, error) {
+	organizations := make(map[string]*Organization)
+	data, err := self.loadAddress(model.OrganizationAddress(orgId))
+	if err != nil || data == nil {
+		return organizations, err
+	}
+
+	container := &organization_pb2.OrganizationContainer{}
+	err = proto.Unmarshal(data, container)
+	if err != nil {
+		return nil, &processor.InternalError{
+			Msg: fmt.Sprintf("Malformed organization data: %v", err)}
+	}
+	for _, entry := range container.GetEntries() {
+		organizations[entry.GetOrgId()] = &Organization{
+			OrgId:           entry.GetOrgId(),
+			Name:            entry.GetName(),
+			Admins:          entry.GetAdmins(),
+			CompanyPrefixes: entry.GetCompanyPrefixes(),
+		}
+	}
+	return organizations, nil
+}
+
+func (self *MdState) storeCompanyPrefixes(prefix string, prefixes map[string]*companyPrefix) error {
+	var keys []string
+	for prefix := range prefixes {
+		keys = append(keys, prefix)
+	}
+	sort.Strings(keys)
+
+	container := &organization_pb2.CompanyPrefixContainer{}
+	for _, key := range keys {
+		container.Entries = append(container.Entries, &organization_pb2.CompanyPrefix{
+			Prefix:     key,
+			OrgId:      prefixes[key].orgId,
+			Extensions: prefixes[key].extensions,
+		})
+	}
+
+	data, err := proto.Marshal(container)
+	if err != nil {
+		return err
+	}
+	return self.storeAddress(model.CompanyPrefixAddress(prefix), data)
+}
+
+func (self *MdState) loadCompanyPrefixes(prefix string) (map[string]*companyPrefix, error) {
+	prefixes := make(map[string]*companyPrefix)
+	data, err := self.loadAddress(model.CompanyPrefixAddress(prefix))
+	if err != nil || data == nil {
+		return prefixes, err
+	}
+
+	container := &organization_pb2.CompanyPrefixContainer{}
+	err = proto.Unmarshal(data, container)
+	if err != nil {
+		return nil, &processor.InternalError{
+			Msg: fmt.Sprintf("Malformed company prefix data: %v", err)}
+	}
+	for _, entry := range container.GetEntries() {
+		prefixes[entry.GetPrefix()] = &companyPrefix{
+			orgId:      entry.GetOrgId(),
+			extensions: entry.GetExtensions(),
+		}
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,181 @@
+package mdata_state
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/model"
+	"github.com/tross-tyson/mdata_go/src/protobuf/organization_pb2"
+)
+
+var testOrganization Organization = Organization{
+	OrgId:           "acme",
+	Name:            "Acme",
+	Admins:          []string{"02aa", "03bb"},
+	CompanyPrefixes: []string{"1234567"},
+}
+var testOrganizationAddress string = model.OrganizationAddress("acme")
+
+func mustMarshal(message proto.Message) []byte {
+	data, err := proto.Marshal(message)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func prefixData(prefix string, orgId string) []byte {
+	return mustMarshal(&organization_pb2.CompanyPrefixContainer{
+		Entries: []*organization_pb2.CompanyPrefix{{Prefix: prefix, OrgId: orgId}},
+	})
+}
+
+func organizationData(organization *Organization) []byte {
+	return mustMarshal(&organization_pb2.OrganizationContainer{
+		Entries: []*organization_pb2.Organization{{
+			OrgId:           organization.OrgId,
+			Name:            organization.Name,
+			Admins:          organization.Admins,
+			CompanyPrefixes: organization.CompanyPrefixes,
+		}},
+	})
+}
+
+func TestGetGtinOwner(t *testing.T) {
+
+	other := Organization{OrgId: "other", Admins: []string{"04cc"}, CompanyPrefixes: []string{"12345678"}}
+
+	tests := map[string]struct {
+		prefixes map[string]string
+		owner    *Organization
+		err      error
+	}{
+		"noOwner": {
+			prefixes: map[string]string{},
+			owner:    nil,
+			err:      nil,
+		},
+		"singleOwner": {
+			prefixes: map[string]string{"1234567": "acme"},
+			owner:    &testOrganization,
+			err:      nil,
+		},
+		"longestPrefixWins": {
+			prefixes: map[string]string{"1234567": "acme", "12345678": "other"},
+			owner:    &other,
+			err:      nil,
+		},
+		"error": {
+			prefixes: nil,
+			owner:    nil,
+			err:      sampleError,
+		},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+
+		testContext := &mockContext{}
+
+		addresses := model.CompanyPrefixAddresses(testGtin)
+		if test.err != nil {
+			testContext.On("GetState", addresses).Return(nil, test.err)
+		} else {
+			returnState := make(map[string][]byte)
+			for prefix, orgId := range test.prefixes {
+				returnState[model.CompanyPrefixAddress(prefix)] = prefixData(prefix, orgId)
+			}
+			testContext.On("GetState", addresses).Return(returnState, nil)
+		}
+		if test.owner != nil {
+			address := model.OrganizationAddress(test.owner.OrgId)
+			testContext.On("GetState", []string{address}).Return(
+				map[string][]byte{address: organizationData(test.owner)},
+				nil,
+			)
+		}
+
+		testState := &MdState{
+			context:      testContext,
+			addressCache: make(map[string][]byte),
+		}
+
+		owner, err := testState.GetGtinOwner(testGtin)
+		assert.Equal(t, test.owner, owner)
+		assert.Equal(t, test.err, err)
+		testContext.AssertExpectations(t)
+	}
+}
+
+func TestSetOrganization(t *testing.T) {
+	testContext := &mockContext{}
+	testContext.On("GetState", []string{testOrganizationAddress}).Return(nil, nil)
+
+	// Admins and prefixes are stored sorted
+	unsorted := Organization{
+		OrgId:           "acme",
+		Name:            "Acme",
+		Admins:          []string{"03bb", "02aa"},
+		CompanyPrefixes: []string{"1234567"},
+	}
+	testContext.On("SetState", map[string][]byte{testOrganizationAddress: organizationData(&testOrganization)}).Return(
+		[]string{testOrganizationAddress},
+		nil,
+	)
+
+	testState := &MdState{
+		context:      testContext,
+		addressCache: make(map[string][]byte),
+	}
+
+	err := testState.SetOrganization("acme", &unsorted)
+	assert.Nil(t, err)
+	organization, err := testState.GetOrganization("acme")
+	assert.Nil(t, err)
+	assert.Equal(t, &testOrganization, organization)
+	testContext.AssertExpectations(t)
+}
+
+func TestCompanyPrefixExtensions(t *testing.T) {
+	state := make(map[string][]byte)
+	testState := NewMdState(newMemoryContext(state))
+
+	assert.Nil(t, testState.SetCompanyPrefixOwner("1234567", "acme"))
+	assert.Nil(t, testState.SetCompanyPrefixOwner("12345678", "acme"))
+	// The shorter prefixes record the owned prefixes extending them
+	assert.Equal(t, mustMarshal(&organization_pb2.CompanyPrefixContainer{
+		Entries: []*organization_pb2.CompanyPrefix{{Prefix: "12345", Extensions: []string{"1234567", "12345678"}}},
+	}), state[model.CompanyPrefixAddress("12345")])
+	owner, err := testState.GetCompanyPrefixOwner("12345")
+	assert.Nil(t, err)
+	assert.Equal(t, "", owner)
+
+	tests := map[string]struct {
+		prefix  string
+		orgId   string
+		overlap string
+		owner   string
+	}{
+		"same":            {prefix: "1234567", orgId: "other", overlap: "1234567", owner: "acme"},
+		"extension":       {prefix: "123456789", orgId: "other", overlap: "1234567", owner: "acme"},
+		"shorter":         {prefix: "12345", orgId: "other", overlap: "1234567", owner: "acme"},
+		"ownOrganization": {prefix: "12345", orgId: "acme"},
+		"unrelated":       {prefix: "1234999", orgId: "other"},
+	}
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		overlap, owner, err := testState.GetOverlappingPrefix(test.prefix, test.orgId)
+		assert.Nil(t, err)
+		assert.Equal(t, test.overlap, overlap)
+		assert.Equal(t, test.owner, owner)
+	}
+
+	// Deleting the prefixes removes their records and the extensions
+	assert.Nil(t, testState.DeleteCompanyPrefix("1234567"))
+	overlap, _, err := testState.GetOverlappingPrefix("12345", "other")
+	assert.Nil(t, err)
+	assert.Equal(t, "12345678", overlap)
+	assert.Nil(t, testState.DeleteCompanyPrefix("12345678"))
+	assert.Empty(t, state)
+}
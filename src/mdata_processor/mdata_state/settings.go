@@ -0,0 +1,67 @@
+package mdata_state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/setting_pb2"
+	"github.com/tross-tyson/mdata_go/src/lifecycle"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+// GetSetting returns the value of a Sawtooth setting, or "" if it is not set
+func (self *MdState) GetSetting(key string) (string, error) {
+	data, err := self.loadAddress(lifecycle.SettingAddress(key))
+	if err != nil || data == nil {
+		return "", err
+	}
+
+	setting := &setting_pb2.Setting{}
+	err = proto.Unmarshal(data, setting)
+	if err != nil {
+		return "", &processor.InternalError{
+			Msg: fmt.Sprintf("Malformed setting data: %v", err)}
+	}
+	for _, entry := range setting.GetEntries() {
+		if entry.GetKey() == key {
+			return entry.GetValue(), nil
+		}
+	}
+	return "", nil
+}
+
+// GetLifecycle returns the product lifecycle configured by the
+// mdata.product.states setting, or lifecycle.Default if it is not set
+func (self *MdState) GetLifecycle() (*lifecycle.Lifecycle, error) {
+	value, err := self.GetSetting(lifecycle.SettingKey)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return lifecycle.Default, nil
+	}
+
+	l, err := lifecycle.Parse(value)
+	if err != nil {
+		return nil, &processor.InvalidTransactionError{
+			Msg: fmt.Sprintf("Invalid %v setting: %v", lifecycle.SettingKey, err)}
+	}
+	return l, nil
+}
+
+// IsRegistrar reports whether publicKey is listed by the mdata.org.registrars
+// setting
+func (self *MdState) IsRegistrar(publicKey string) (bool, error) {
+	value, err := self.GetSetting(model.RegistrarsSetting)
+	if err != nil {
+		return false, err
+	}
+	for _, registrar := range strings.Split(value, ",") {
+		if strings.TrimSpace(registrar) == publicKey && publicKey != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,101 @@
+package mdata_state
+
+import (
+	"testing"
+
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/setting_pb2"
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/lifecycle"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+func settingData(key string, value string) []byte {
+	return mustMarshal(&setting_pb2.Setting{
+		Entries: []*setting_pb2.Setting_Entry{{Key: key, Value: value}},
+	})
+}
+
+func TestGetLifecycle(t *testing.T) {
+
+	address := lifecycle.SettingAddress(lifecycle.SettingKey)
+	configured := &lifecycle.Lifecycle{
+		InitialState: "DRAFT",
+		Transitions:  map[string][]string{"DRAFT": {"PUBLISHED", lifecycle.StateDeleted}, "PUBLISHED": {}},
+	}
+
+	tests := map[string]struct {
+		state     map[string][]byte
+		lifecycle *lifecycle.Lifecycle
+		err       error
+	}{
+		"notSet": {
+			state:     map[string][]byte{},
+			lifecycle: lifecycle.Default,
+			err:       nil,
+		},
+		"configured": {
+			state:     map[string][]byte{address: settingData(lifecycle.SettingKey, "DRAFT:PUBLISHED,DELETED;PUBLISHED")},
+			lifecycle: configured,
+			err:       nil,
+		},
+		"otherKeyAtAddress": { //Settings collide on an address like products do
+			state:     map[string][]byte{address: settingData("mdata.product.other", "DRAFT")},
+			lifecycle: lifecycle.Default,
+			err:       nil,
+		},
+		"malformed": {
+			state:     map[string][]byte{address: settingData(lifecycle.SettingKey, "ACTIVE:RETIRED")},
+			lifecycle: nil,
+			err:       &processor.InvalidTransactionError{},
+		},
+		"error": {
+			state:     nil,
+			lifecycle: nil,
+			err:       sampleError,
+		},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+
+		testContext := &mockContext{}
+		if name == "error" {
+			testContext.On("GetState", []string{address}).Return(nil, sampleError)
+		} else {
+			testContext.On("GetState", []string{address}).Return(test.state, nil)
+		}
+
+		testState := &MdState{
+			context:      testContext,
+			addressCache: make(map[string][]byte),
+		}
+
+		l, err := testState.GetLifecycle()
+		assert.Equal(t, test.lifecycle, l)
+		if test.err == sampleError {
+			assert.Equal(t, sampleError, err)
+		} else if test.err != nil {
+			assert.IsType(t, test.err, err)
+		} else {
+			assert.Nil(t, err)
+		}
+		testContext.AssertExpectations(t)
+	}
+}
+
+func TestIsRegistrar(t *testing.T) {
+	address := lifecycle.SettingAddress(model.RegistrarsSetting)
+	testState := NewMdState(newMemoryContext(map[string][]byte{
+		address: settingData(model.RegistrarsSetting, "02aa, 03bb"),
+	}))
+	for publicKey, expected := range map[string]bool{"02aa": true, "03bb": true, "04cc": false, "": false} {
+		registrar, err := testState.IsRegistrar(publicKey)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, registrar, publicKey)
+	}
+
+	registrar, err := NewMdState(newMemoryContext(map[string][]byte{})).IsRegistrar("02aa")
+	assert.Nil(t, err)
+	assert.False(t, registrar)
+}
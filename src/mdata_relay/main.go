@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hyperledger/sawtooth-sdk-go/logging"
+	flags "github.com/jessevdk/go-flags"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/mdata_relay/relay"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+type Opts struct {
+	Verbose []bool `short:"v" long:"verbose" description:"Increase verbosity"`
+	Connect string `short:"C" long:"connect" description:"Validator component endpoint to connect to" default:"tcp://localhost:4004"`
+	Config  string `short:"c" long:"config" description:"Webhook configuration file" default:"/etc/sawtooth/mdata_relay.yaml"`
+	Cursor  string `long:"cursor" description:"File recording the last blocks delivered" default:"/var/lib/mdata_relay/cursor"`
+}
+
+func main() {
+	var opts Opts
+
+	logger := logging.Get()
+
+	parser := flags.NewParser(&opts, flags.Default)
+	remaining, err := parser.Parse()
+	if err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		} else {
+			logger.Errorf("Failed to parse args: %v", err)
+			os.Exit(2)
+		}
+	}
+
+	if len(remaining) > 0 {
+		fmt.Printf("Error: Unrecognized arguments passed: %v\n", remaining)
+		os.Exit(2)
+	}
+
+	switch len(opts.Verbose) {
+	case 2:
+		logger.SetLevel(logging.DEBUG)
+	case 1:
+		logger.SetLevel(logging.INFO)
+	default:
+		logger.SetLevel(logging.WARN)
+	}
+
+	config, err := relay.LoadConfig(opts.Config)
+	if err != nil {
+		logger.Error(err)
+		os.Exit(1)
+	}
+	cursor := relay.NewCursor(opts.Cursor)
+	knownBlockIds, err := cursor.Load()
+	if err != nil {
+		logger.Errorf("Failed to read cursor: %v", err)
+		os.Exit(1)
+	}
+	logger.Infof("Relaying to %v endpoints after blocks %v", len(config.Endpoints), knownBlockIds)
+
+	stream, err := client.Subscribe(opts.Connect, client.EventFilter{EventTypes: relay.EventTypes(config)}, knownBlockIds)
+	if err != nil {
+		logger.Error(err)
+		os.Exit(1)
+	}
+
+	// The stream is not closed: Run leaves Next waiting for a block, and
+	// exiting disconnects from the validator
+	mdataRelay := relay.NewRelay(config, cursor)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		mdataRelay.Stop()
+	}()
+
+	err = mdataRelay.Run(stream)
+	if err != nil {
+		logger.Error("Relay stopped: ", err)
+		os.Exit(1)
+	}
+}
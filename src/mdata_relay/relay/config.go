@@ -0,0 +1,113 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"github.com/tross-tyson/mdata_go/src/model"
+	"gopkg.in/yaml.v2"
+)
+
+// Retry defaults of an endpoint that sets none
+const (
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = 5 * time.Minute
+	DefaultTimeout        = 10 * time.Second
+)
+
+// Config lists the webhooks product events are relayed to
+type Config struct {
+	Endpoints []*Endpoint `yaml:"endpoints"`
+}
+
+// Endpoint is a webhook notified of product events
+type Endpoint struct {
+	Url string `yaml:"url"`
+	// Key of the HMAC-SHA256 signature sent with every notification
+	Secret string `yaml:"secret"`
+	// Event types to notify, every product event when empty
+	EventTypes []string `yaml:"event_types"`
+	// Delay before the first retry of a failed notification, doubled on
+	// every further attempt up to MaxBackoff
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	// Attempts made before a notification is dropped, 0 to retry until it
+	// is delivered
+	MaxAttempts uint `yaml:"max_attempts"`
+	// Time allowed for each request
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// LoadConfig reads the YAML configuration at path
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	err = yaml.UnmarshalStrict(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %v: %v", path, err)
+	}
+	err = config.validate()
+	if err != nil {
+		return nil, fmt.Errorf("Error in %v: %v", path, err)
+	}
+	return config, nil
+}
+
+// validate checks every endpoint and fills in the retry defaults
+func (config *Config) validate() error {
+	if len(config.Endpoints) == 0 {
+		return errors.New("No endpoints configured")
+	}
+	for _, endpoint := range config.Endpoints {
+		parsed, err := url.Parse(endpoint.Url)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("Endpoint url '%v' is not an http or https URL", endpoint.Url)
+		}
+		for _, eventType := range endpoint.EventTypes {
+			if !isEventType(eventType) {
+				return fmt.Errorf("Endpoint %v: unknown event type '%v'", endpoint.Url, eventType)
+			}
+		}
+		if endpoint.InitialBackoff == 0 {
+			endpoint.InitialBackoff = DefaultInitialBackoff
+		}
+		if endpoint.MaxBackoff == 0 {
+			endpoint.MaxBackoff = DefaultMaxBackoff
+		}
+		if endpoint.MaxBackoff < endpoint.InitialBackoff {
+			endpoint.MaxBackoff = endpoint.InitialBackoff
+		}
+		if endpoint.Timeout == 0 {
+			endpoint.Timeout = DefaultTimeout
+		}
+	}
+	return nil
+}
+
+// Accepts reports whether the endpoint is notified of events of eventType
+func (endpoint *Endpoint) Accepts(eventType string) bool {
+	if len(endpoint.EventTypes) == 0 {
+		return true
+	}
+	for _, accepted := range endpoint.EventTypes {
+		if accepted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func isEventType(eventType string) bool {
+	for _, known := range model.EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
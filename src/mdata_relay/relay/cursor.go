@@ -0,0 +1,72 @@
+package relay
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Block ids kept in the cursor, so a restarted relay finds the newest
+// delivered block still on the chain
+const cursorBlockIds = 20
+
+// Cursor records on disk the last blocks whose events were delivered to every
+// endpoint, so a restarted relay resumes after the newest of them still on
+// the chain
+type Cursor struct {
+	path string
+}
+
+func NewCursor(path string) *Cursor {
+	return &Cursor{path: path}
+}
+
+// Load returns the block ids stored in the cursor, newest first, or nil when
+// none was stored yet
+func (cursor *Cursor) Load() ([]string, error) {
+	data, err := ioutil.ReadFile(cursor.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// Save stores blockId before the newest block ids already stored, one per
+// line. The cursor is written to a temporary file renamed over the old one,
+// so a crash leaves either the old or the new block ids.
+func (cursor *Cursor) Save(blockId string) error {
+	blockIds, err := cursor.Load()
+	if err != nil {
+		return err
+	}
+	if len(blockIds) > 0 && blockIds[0] == blockId {
+		return nil
+	}
+	blockIds = append([]string{blockId}, blockIds...)
+	if len(blockIds) > cursorBlockIds {
+		blockIds = blockIds[:cursorBlockIds]
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(cursor.path), filepath.Base(cursor.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(strings.Join(blockIds, "\n") + "\n")
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), cursor.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
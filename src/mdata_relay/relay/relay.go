@@ -0,0 +1,151 @@
+// Package relay delivers the product events committed on a Sawtooth
+// validator to webhooks, as signed JSON notifications.
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/sawtooth-sdk-go/logging"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+)
+
+var logger *logging.Logger = logging.Get()
+
+// EventSource yields the product events of each committed block, as
+// client.EventStream does
+type EventSource interface {
+	Next() (*client.BlockEvents, error)
+}
+
+// Relay notifies every configured endpoint of the product events of each
+// block, and then moves its cursor to that block. Blocks are relayed as they
+// are committed, so they may belong to a fork the validator later abandons.
+type Relay struct {
+	webhooks []*webhook
+	cursor   *Cursor
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewRelay(config *Config, cursor *Cursor) *Relay {
+	relay := &Relay{cursor: cursor, stop: make(chan struct{})}
+	for _, endpoint := range config.Endpoints {
+		relay.webhooks = append(relay.webhooks, newWebhook(endpoint))
+	}
+	return relay
+}
+
+// EventTypes returns the event types any endpoint is notified of, or nil
+// when some endpoint is notified of every event
+func EventTypes(config *Config) []string {
+	var eventTypes []string
+	seen := make(map[string]bool)
+	for _, endpoint := range config.Endpoints {
+		if len(endpoint.EventTypes) == 0 {
+			return nil
+		}
+		for _, eventType := range endpoint.EventTypes {
+			if !seen[eventType] {
+				seen[eventType] = true
+				eventTypes = append(eventTypes, eventType)
+			}
+		}
+	}
+	return eventTypes
+}
+
+// Run delivers the events of the blocks read from source until Stop is
+// called or source fails. A block interrupted by Stop is delivered again
+// after a restart, since the cursor only moves once every endpoint was
+// notified.
+func (relay *Relay) Run(source EventSource) error {
+	// Next blocks until a block is committed, so blocks are read in the
+	// background while waiting for Stop
+	blocks := make(chan *client.BlockEvents)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			block, err := source.Next()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case blocks <- block:
+			case <-relay.stop:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case block := <-blocks:
+			err := relay.Deliver(block)
+			if err == errStopped {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		case err := <-errs:
+			return err
+		case <-relay.stop:
+			return nil
+		}
+	}
+}
+
+// Stop ends Run, interrupting the retries of undelivered notifications
+func (relay *Relay) Stop() {
+	relay.stopOnce.Do(func() { close(relay.stop) })
+}
+
+// Deliver notifies the endpoints of the events of block, each endpoint in
+// block order while the endpoints are notified concurrently, and then saves
+// the block in the cursor
+func (relay *Relay) Deliver(block *client.BlockEvents) error {
+	bodies := make([][]byte, len(block.Events))
+	for i, event := range block.Events {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("Error encoding event: %v", err)
+		}
+		bodies[i] = body
+	}
+
+	stopped := make([]bool, len(relay.webhooks))
+	var wait sync.WaitGroup
+	for i, hook := range relay.webhooks {
+		wait.Add(1)
+		go func(i int, hook *webhook) {
+			defer wait.Done()
+			for j, event := range block.Events {
+				if !hook.endpoint.Accepts(event.EventType) {
+					continue
+				}
+				id := fmt.Sprintf("%v-%v", block.BlockId, j)
+				err := hook.deliver(relay.stop, id, event.EventType, bodies[j])
+				if err == errStopped {
+					stopped[i] = true
+					return
+				}
+				if err != nil {
+					// One failing endpoint must not hold back the others
+					logger.Errorf("Dropped notification %v to %v: %v", id, hook.endpoint.Url, err)
+				}
+			}
+		}(i, hook)
+	}
+	wait.Wait()
+
+	for _, s := range stopped {
+		if s {
+			return errStopped
+		}
+	}
+	logger.Debugf("Delivered %v events of block %v", len(block.Events), block.BlockNum)
+	return relay.cursor.Save(block.BlockId)
+}
@@ -0,0 +1,223 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+	"github.com/tross-tyson/mdata_go/src/mdata_relay/relaytest"
+	"github.com/tross-tyson/mdata_go/src/model"
+)
+
+var testBlock = &client.BlockEvents{
+	BlockId:  "b2",
+	BlockNum: 7,
+	Events: []*client.ProductEvent{
+		{BlockId: "b2", BlockNum: 7, EventType: model.EventProductCreated, Gtin: "00012345600012", Signer: "02aa", State: "ACTIVE",
+			Attributes: map[string]string{"uom": "cases"}},
+		{BlockId: "b2", BlockNum: 7, EventType: model.EventStateChanged, Gtin: "00012345600029", Signer: "02aa", State: "INACTIVE",
+			PreviousState: "ACTIVE", Attributes: map[string]string{}},
+	},
+}
+
+func testEndpoint(url string, eventTypes ...string) *Endpoint {
+	return &Endpoint{
+		Url:            url,
+		Secret:         "secret",
+		EventTypes:     eventTypes,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     4 * time.Millisecond,
+		Timeout:        time.Second,
+	}
+}
+
+func tempCursor(t *testing.T) (*Cursor, func()) {
+	dir, err := ioutil.TempDir("", "mdata_relay")
+	assert.Nil(t, err)
+	return NewCursor(filepath.Join(dir, "cursor")), func() { os.RemoveAll(dir) }
+}
+
+func TestDeliver(t *testing.T) {
+	all := relaytest.NewStub("secret")
+	defer all.Close()
+	stateChanges := relaytest.NewStub("secret")
+	defer stateChanges.Close()
+	// The first attempts fail and are retried
+	all.Fail(2, http.StatusServiceUnavailable)
+
+	cursor, cleanup := tempCursor(t)
+	defer cleanup()
+	relay := NewRelay(&Config{Endpoints: []*Endpoint{
+		testEndpoint(all.URL),
+		testEndpoint(stateChanges.URL, model.EventStateChanged),
+	}}, cursor)
+
+	assert.Nil(t, relay.Deliver(testBlock))
+
+	notifications := all.Notifications()
+	assert.Equal(t, 4, all.Attempts())
+	assert.Len(t, notifications, 2)
+	assert.Equal(t, "b2-0", notifications[0].Id)
+	assert.Equal(t, model.EventProductCreated, notifications[0].EventType)
+	assert.Equal(t, testBlock.Events[0], notifications[0].Event)
+	assert.True(t, notifications[0].Signed)
+	assert.Equal(t, "b2-1", notifications[1].Id)
+
+	notifications = stateChanges.Notifications()
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, "b2-1", notifications[0].Id)
+	assert.Equal(t, testBlock.Events[1], notifications[0].Event)
+
+	blockIds, err := cursor.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b2"}, blockIds)
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	tests := map[string]struct {
+		status      int
+		maxAttempts uint
+		attempts    int
+	}{
+		"rejected": {
+			status:   http.StatusBadRequest,
+			attempts: 2,
+		},
+		"attemptsExhausted": {
+			status:      http.StatusInternalServerError,
+			maxAttempts: 3,
+			attempts:    4,
+		},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		stub := relaytest.NewStub("secret")
+		cursor, cleanup := tempCursor(t)
+		endpoint := testEndpoint(stub.URL)
+		endpoint.MaxAttempts = test.maxAttempts
+		stub.Fail(test.attempts-1, test.status)
+
+		// The first event is dropped, the second is delivered and the cursor
+		// moves past the block
+		assert.Nil(t, NewRelay(&Config{Endpoints: []*Endpoint{endpoint}}, cursor).Deliver(testBlock))
+		assert.Equal(t, test.attempts, stub.Attempts())
+		assert.Len(t, stub.Notifications(), 1)
+		assert.Equal(t, "b2-1", stub.Notifications()[0].Id)
+		blockIds, _ := cursor.Load()
+		assert.Equal(t, []string{"b2"}, blockIds)
+
+		stub.Close()
+		cleanup()
+	}
+}
+
+type testSource struct {
+	blocks []*client.BlockEvents
+}
+
+func (source *testSource) Next() (*client.BlockEvents, error) {
+	if len(source.blocks) == 0 {
+		return nil, errors.New("Connection closed")
+	}
+	block := source.blocks[0]
+	source.blocks = source.blocks[1:]
+	return block, nil
+}
+
+func TestRun(t *testing.T) {
+	stub := relaytest.NewStub("secret")
+	defer stub.Close()
+	cursor, cleanup := tempCursor(t)
+	defer cleanup()
+
+	relay := NewRelay(&Config{Endpoints: []*Endpoint{testEndpoint(stub.URL)}}, cursor)
+	err := relay.Run(&testSource{blocks: []*client.BlockEvents{testBlock, {BlockId: "b3", BlockNum: 8}}})
+	assert.EqualError(t, err, "Connection closed")
+	assert.Len(t, stub.Notifications(), 2)
+	blockIds, _ := cursor.Load()
+	assert.Equal(t, []string{"b3", "b2"}, blockIds)
+
+	// A stopped relay leaves the cursor at the last block delivered to every
+	// endpoint
+	stub.Fail(1000, http.StatusServiceUnavailable)
+	relay = NewRelay(&Config{Endpoints: []*Endpoint{testEndpoint(stub.URL)}}, cursor)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		relay.Stop()
+	}()
+	assert.Nil(t, relay.Run(&testSource{blocks: []*client.BlockEvents{{BlockId: "b4", BlockNum: 9, Events: testBlock.Events}}}))
+	blockIds, _ = cursor.Load()
+	assert.Equal(t, []string{"b3", "b2"}, blockIds)
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mdata_relay")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := map[string]struct {
+		in    string
+		fails bool
+	}{
+		"valid": {
+			in: "endpoints:\n- url: https://erp.example.com/hooks/mdata\n  secret: s\n  event_types: [mdata/state_changed]\n  max_backoff: 1m\n",
+		},
+		"noEndpoints":  {in: "endpoints: []\n", fails: true},
+		"badUrl":       {in: "endpoints:\n- url: erp.example.com\n", fails: true},
+		"badEventType": {in: "endpoints:\n- url: http://localhost:9000\n  event_types: [product_created]\n", fails: true},
+		"unknownField": {in: "endpoints:\n- url: http://localhost:9000\n  retries: 3\n", fails: true},
+	}
+
+	for name, test := range tests {
+		t.Logf("Running test case: %s", name)
+		path := filepath.Join(dir, name+".yaml")
+		assert.Nil(t, ioutil.WriteFile(path, []byte(test.in), 0600))
+		config, err := LoadConfig(path)
+		if test.fails {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		endpoint := config.Endpoints[0]
+		assert.Equal(t, DefaultInitialBackoff, endpoint.InitialBackoff)
+		assert.Equal(t, time.Minute, endpoint.MaxBackoff)
+		assert.Equal(t, DefaultTimeout, endpoint.Timeout)
+		assert.True(t, endpoint.Accepts(model.EventStateChanged))
+		assert.False(t, endpoint.Accepts(model.EventProductCreated))
+	}
+}
+
+func TestCursor(t *testing.T) {
+	cursor, cleanup := tempCursor(t)
+	defer cleanup()
+
+	blockIds, err := cursor.Load()
+	assert.Nil(t, err)
+	assert.Empty(t, blockIds)
+
+	assert.Nil(t, cursor.Save("b1"))
+	assert.Nil(t, cursor.Save("b2"))
+	// A block delivered again after a restart is stored once
+	assert.Nil(t, cursor.Save("b2"))
+	blockIds, err = cursor.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b2", "b1"}, blockIds)
+
+	// Only the newest block ids are kept
+	for i := 3; i <= cursorBlockIds+5; i++ {
+		assert.Nil(t, cursor.Save(fmt.Sprintf("b%v", i)))
+	}
+	blockIds, err = cursor.Load()
+	assert.Nil(t, err)
+	assert.Len(t, blockIds, cursorBlockIds)
+	assert.Equal(t, fmt.Sprintf("b%v", cursorBlockIds+5), blockIds[0])
+	assert.Equal(t, "b6", blockIds[cursorBlockIds-1])
+}
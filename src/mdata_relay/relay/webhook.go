@@ -0,0 +1,110 @@
+package relay
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Headers of a notification. Notifications are delivered at least once, a
+// receiver can drop one whose event id it already handled.
+const (
+	// "sha256=" and the hex HMAC-SHA256 of the body keyed with the endpoint's secret
+	SignatureHeader = "X-Mdata-Signature"
+	// Block id and the event's position in the block
+	EventIdHeader   = "X-Mdata-Event-Id"
+	EventTypeHeader = "X-Mdata-Event-Type"
+)
+
+// errStopped is returned by deliveries interrupted by Relay.Stop
+var errStopped = errors.New("Relay stopped")
+
+// Sign returns the SignatureHeader value of body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhook posts notifications to an endpoint
+type webhook struct {
+	endpoint *Endpoint
+	client   *http.Client
+}
+
+func newWebhook(endpoint *Endpoint) *webhook {
+	return &webhook{endpoint: endpoint, client: &http.Client{Timeout: endpoint.Timeout}}
+}
+
+// deliver posts a notification until the endpoint accepts it, waiting longer
+// after every failed attempt. It gives up when the endpoint rejects the
+// notification as invalid or after the endpoint's MaxAttempts, and returns
+// errStopped when stop is closed first.
+func (hook *webhook) deliver(stop <-chan struct{}, id string, eventType string, body []byte) error {
+	backoff := hook.endpoint.InitialBackoff
+	for attempt := uint(1); ; attempt++ {
+		retry, err := hook.post(id, eventType, body)
+		if err == nil {
+			return nil
+		}
+		if !retry {
+			return err
+		}
+		if hook.endpoint.MaxAttempts != 0 && attempt >= hook.endpoint.MaxAttempts {
+			return fmt.Errorf("%v, giving up after %v attempts", err, attempt)
+		}
+		logger.Warnf("Notifying %v of %v failed, retrying in %v: %v", hook.endpoint.Url, id, backoff, err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-stop:
+			timer.Stop()
+			return errStopped
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > hook.endpoint.MaxBackoff {
+			backoff = hook.endpoint.MaxBackoff
+		}
+	}
+}
+
+// post makes one attempt at delivering a notification, reporting whether a
+// failed attempt is worth retrying
+func (hook *webhook) post(id string, eventType string, body []byte) (bool, error) {
+	request, err := http.NewRequest("POST", hook.endpoint.Url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(SignatureHeader, Sign(hook.endpoint.Secret, body))
+	request.Header.Set(EventIdHeader, id)
+	request.Header.Set(EventTypeHeader, eventType)
+
+	response, err := hook.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	// Read the body so the connection is reused
+	io.Copy(ioutil.Discard, response.Body)
+	response.Body.Close()
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return false, nil
+	case response.StatusCode == http.StatusRequestTimeout || response.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("Endpoint returned %v", response.Status)
+	case response.StatusCode >= 400 && response.StatusCode < 500:
+		// The endpoint will not accept the notification however often it is sent
+		return false, fmt.Errorf("Endpoint rejected the notification: %v", response.Status)
+	default:
+		return true, fmt.Errorf("Endpoint returned %v", response.Status)
+	}
+}
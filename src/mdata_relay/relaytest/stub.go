@@ -0,0 +1,111 @@
+// Package relaytest provides a webhook endpoint recording the notifications
+// of mdata_relay, for tests and for trying the relay against a validator.
+package relaytest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/tross-tyson/mdata_go/src/mdata_client/client"
+)
+
+// Notification is a notification received by a Stub
+type Notification struct {
+	Id        string
+	EventType string
+	Event     *client.ProductEvent
+	// Whether the signature matched the Stub's secret
+	Signed bool
+}
+
+// Stub is a webhook endpoint that accepts every notification unless told to
+// fail
+type Stub struct {
+	Server *httptest.Server
+	// URL of the endpoint
+	URL string
+
+	secret        string
+	mutex         sync.Mutex
+	failures      int
+	failStatus    int
+	attempts      int
+	notifications []Notification
+}
+
+// NewStub starts a Stub on a local port, checking signatures against secret.
+// Close stops it.
+func NewStub(secret string) *Stub {
+	stub := &Stub{secret: secret}
+	stub.Server = httptest.NewServer(http.HandlerFunc(stub.serve))
+	stub.URL = stub.Server.URL
+	return stub
+}
+
+// Close stops the endpoint
+func (stub *Stub) Close() {
+	stub.Server.Close()
+}
+
+// Fail answers the next n notifications with status instead of accepting them
+func (stub *Stub) Fail(n int, status int) {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	stub.failures = n
+	stub.failStatus = status
+}
+
+// Notifications returns the notifications accepted so far, in the order
+// they arrived
+func (stub *Stub) Notifications() []Notification {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	return append([]Notification{}, stub.notifications...)
+}
+
+// Attempts returns the number of requests received, failed ones included
+func (stub *Stub) Attempts() int {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	return stub.attempts
+}
+
+func (stub *Stub) serve(w http.ResponseWriter, r *http.Request) {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	stub.attempts++
+	if stub.failures > 0 {
+		stub.failures--
+		w.WriteHeader(stub.failStatus)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	event := &client.ProductEvent{}
+	err = json.Unmarshal(body, event)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	mac := hmac.New(sha256.New, []byte(stub.secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	stub.notifications = append(stub.notifications, Notification{
+		Id:        r.Header.Get("X-Mdata-Event-Id"),
+		EventType: r.Header.Get("X-Mdata-Event-Type"),
+		Event:     event,
+		Signed:    hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Mdata-Signature"))),
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
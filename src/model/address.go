@@ -0,0 +1,133 @@
+package model
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+/*
+	Namespace prefix is six hex characters, or three bytes
+
+All data under a namespace prefix follows a consistent address and data encoding/serialization schem that is determined
+by the transaction family which defines the namespace
+*/
+var Namespace = hexdigest("mdata")[:6]
+
+// ReservedPrefix starts the addresses of every record other than a product.
+// Product addresses predate it and use all 64 characters after the namespace
+// for the GTIN hash, so the reserved range is long enough that no product is
+// expected to fall in it. Each record type adds a two character type prefix.
+var ReservedPrefix = Namespace + "ffffffff"
+
+// Type prefixes of the records in the reserved range, after ReservedPrefix
+const (
+	organizationType  = "01"
+	companyPrefixType = "02"
+	agentType         = "03"
+	historyType       = "04"
+)
+
+// ProductAddress returns the address products with gtin are stored at
+func ProductAddress(gtin string) string {
+	return Namespace + hexdigest(gtin)[:64]
+}
+
+// ProductAddressReserved reports whether the product address of gtin falls in
+// the range reserved for records other than products. Such a GTIN cannot be
+// stored; the chance of it is 1 in 2^32.
+func ProductAddressReserved(gtin string) bool {
+	return IsReserved(ProductAddress(gtin))
+}
+
+// IsReserved reports whether address holds a record other than a product
+func IsReserved(address string) bool {
+	return strings.HasPrefix(address, ReservedPrefix)
+}
+
+// OrganizationAddress returns the address of the organization orgId
+func OrganizationAddress(orgId string) string {
+	return reservedAddress(organizationType, orgId)
+}
+
+// CompanyPrefixAddress returns the address recording the owner of a GS1
+// company prefix
+func CompanyPrefixAddress(prefix string) string {
+	return reservedAddress(companyPrefixType, prefix)
+}
+
+// GS1 company prefixes are 4 to 12 digits long. In a GTIN-14 they follow the
+// indicator digit.
+const (
+	MinCompanyPrefixLength = 4
+	MaxCompanyPrefixLength = 12
+)
+
+// CompanyPrefixCandidates returns every company prefix a GTIN-14 could carry,
+// shortest first.
+func CompanyPrefixCandidates(gtin string) []string {
+	var candidates []string
+	for length := MinCompanyPrefixLength; length <= MaxCompanyPrefixLength && length < len(gtin)-1; length++ {
+		candidates = append(candidates, gtin[1:1+length])
+	}
+	return candidates
+}
+
+// CompanyPrefixAddresses returns the addresses of the company prefixes gtin
+// could carry, which the processor reads to find the organization owning a
+// product. Transactions acting on the product list them as inputs.
+func CompanyPrefixAddresses(gtin string) []string {
+	var addresses []string
+	for _, prefix := range CompanyPrefixCandidates(gtin) {
+		addresses = append(addresses, CompanyPrefixAddress(prefix))
+	}
+	return addresses
+}
+
+// AgentAddress returns the address of the agent with publicKey
+func AgentAddress(publicKey string) string {
+	return reservedAddress(agentType, publicKey)
+}
+
+// HistoryPageSize is the number of entries of a product's change history
+// stored at one address. The history is split into pages so that appending to
+// it reads and writes a bounded amount of state.
+const HistoryPageSize = 100
+
+// HistoryAddress returns the address of the page of the change history of gtin
+// holding the entry of version, counting from 1. The page number ends the
+// address, so pages sort in version order.
+func HistoryAddress(gtin string, version uint64) string {
+	page := uint64(0)
+	if version > 0 {
+		page = (version - 1) / HistoryPageSize
+	}
+	return HistoryPrefix(gtin) + fmt.Sprintf("%08x", page)
+}
+
+// HistoryPrefix returns the address prefix shared by every page of the change
+// history of gtin
+func HistoryPrefix(gtin string) string {
+	prefix := ReservedPrefix + historyType
+	return prefix + hexdigest(gtin)[:62-len(prefix)]
+}
+
+// IsHistoryAddress reports whether address holds product histories
+func IsHistoryAddress(address string) bool {
+	return strings.HasPrefix(address, ReservedPrefix+historyType)
+}
+
+// reservedAddress builds a 70 character address in the reserved range:
+// ReservedPrefix, the record type and the start of the key's hash.
+func reservedAddress(recordType string, key string) string {
+	prefix := ReservedPrefix + recordType
+	return prefix + hexdigest(key)[:70-len(prefix)]
+}
+
+func hexdigest(str string) string {
+	hash := sha512.New()
+	hash.Write([]byte(str))
+	hashBytes := hash.Sum(nil)
+	return strings.ToLower(hex.EncodeToString(hashBytes))
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// Attributes are the key/value pairs describing a product
+type Attributes map[string]string
+
+// Characters with a meaning in the serialized form of Attributes. They are
+// escaped with a backslash when they appear in a key or value.
+const attributeSpecialChars = "\\,=|"
+
+// serialize writes the attributes as "key=value,key=value" in key order, so
+// equal attributes always produce identical bytes on every node.
+func (self Attributes) serialize() []byte {
+	var b bytes.Buffer
+	for i, k := range self.Keys() {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(AttributeEntry(k, self[k]))
+	}
+	return b.Bytes()
+}
+
+func (self Attributes) String() string {
+	return string(self.serialize())
+}
+
+// Keys returns the attribute keys in sorted order
+func (self Attributes) Keys() []string {
+	keys := make([]string, 0, len(self))
+	for k := range self {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AttributeEntry formats a single "key=value" entry as read by
+// DeserializeAttributes, escaping the key and value.
+func AttributeEntry(key string, value string) string {
+	return escapeAttribute(key) + "=" + escapeAttribute(value)
+}
+
+// SplitAttributes splits serialized attributes into the entries accepted by
+// DeserializeAttributes.
+func SplitAttributes(data string) []string {
+	var entries []string
+	start := 0
+	for i := 0; i < len(data); i++ {
+		if data[i] == '\\' {
+			i++
+		} else if data[i] == ',' {
+			entries = append(entries, data[start:i])
+			start = i + 1
+		}
+	}
+	return append(entries, data[start:])
+}
+
+func DeserializeAttributes(a []string) Attributes {
+	A := Attributes{}
+	for _, str := range a {
+		if str != "" {
+			k, v := str, ""
+			if i := indexUnescaped(str, '='); i >= 0 {
+				k, v = str[:i], str[i+1:]
+			}
+			A[unescapeAttribute(k)] = unescapeAttribute(v)
+		}
+	}
+
+	return A
+}
+
+func escapeAttribute(str string) string {
+	var b strings.Builder
+	for i := 0; i < len(str); i++ {
+		if strings.IndexByte(attributeSpecialChars, str[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(str[i])
+	}
+	return b.String()
+}
+
+// unescapeAttribute reverses escapeAttribute. A backslash that does not
+// precede a special character is kept, so unescaped legacy data reads as is.
+func unescapeAttribute(str string) string {
+	var b strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' && i+1 < len(str) && strings.IndexByte(attributeSpecialChars, str[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(str[i])
+	}
+	return b.String()
+}
+
+// indexUnescaped returns the index of the first c in str not preceded by an
+// escaping backslash, or -1.
+func indexUnescaped(str string, c byte) int {
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' {
+			i++
+		} else if str[i] == c {
+			return i
+		}
+	}
+	return -1
+}
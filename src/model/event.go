@@ -0,0 +1,45 @@
+package model
+
+// Types of the events the transaction processor emits when a product changes.
+// A transaction that changes a product's state and other fields, such as an
+// update reactivating a discontinued product, emits both EventStateChanged and
+// EventProductUpdated.
+const (
+	EventProductCreated = "mdata/product_created"
+	EventProductUpdated = "mdata/product_updated"
+	EventStateChanged   = "mdata/state_changed"
+	EventProductDeleted = "mdata/product_deleted"
+)
+
+// EventTypes lists every product event type
+var EventTypes = []string{EventProductCreated, EventProductUpdated, EventStateChanged, EventProductDeleted}
+
+// Attributes of product events, which subscribers can filter on. Each of the
+// product's attributes is an event attribute too, its key prefixed with
+// EventAttributePrefix. The event data is the product encoded by
+// EncodeProducts, as it was before a delete and after any other change.
+const (
+	EventGtin          = "gtin"
+	EventSigner        = "signer"
+	EventState         = "state"
+	EventPreviousState = "previous_state"
+	// Prefix of the event attributes holding product attributes
+	EventAttributePrefix = "attr."
+)
+
+// Types of the events the transaction processor emits when an organization or
+// an agent is stored. The event data is the organization_pb2.Organization or
+// organization_pb2.Agent message stored.
+const (
+	EventOrgCreated   = "mdata/org_created"
+	EventOrgUpdated   = "mdata/org_updated"
+	EventAgentCreated = "mdata/agent_created"
+	EventAgentUpdated = "mdata/agent_updated"
+)
+
+// Attributes of organization and agent events, besides EventSigner. Agent
+// events carry both.
+const (
+	EventOrgId     = "org_id"
+	EventPublicKey = "public_key"
+)
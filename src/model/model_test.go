@@ -0,0 +1,137 @@
+package model
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializeProducts(t *testing.T) {
+	// Values the pipe-delimited encoding could not hold survive a round trip
+	products := []*Product{
+		{Gtin: "00012345600012", Attributes: Attributes{"desc": "cheese, cheddar", "ratio": "a=b", "sep": "|"}, State: "ACTIVE", Creator: "02aa", Owners: []string{"02aa", "03bb"}, Version: 3},
+		{Gtin: "00012345600029", Attributes: Attributes{}, State: "INACTIVE"},
+	}
+
+	data, err := EncodeProducts(products)
+	assert.Nil(t, err)
+
+	result, err := DecodeProducts(data)
+	assert.Nil(t, err)
+	assert.Equal(t, products, result)
+
+	// The encoding does not depend on the order products are passed in
+	again, err := EncodeProducts([]*Product{products[1], products[0]})
+	assert.Nil(t, err)
+	assert.Equal(t, data, again)
+}
+
+func TestDecodeLegacyProducts(t *testing.T) {
+	products, err := DecodeProducts([]byte(`00012345600029,,INACTIVE|00012345600012,uom=cases,path=C:\dir,ACTIVE`))
+	assert.Nil(t, err)
+	assert.Equal(t, []*Product{
+		{Gtin: "00012345600012", Attributes: Attributes{"uom": "cases", "path": `C:\dir`}, State: "ACTIVE"},
+		{Gtin: "00012345600029", Attributes: Attributes{}, State: "INACTIVE"},
+	}, products)
+
+	_, err = DecodeProducts([]byte("00012345600012,ACTIVE"))
+	assert.NotNil(t, err)
+}
+
+func TestCompanyPrefixCandidates(t *testing.T) {
+	assert.Equal(t, []string{
+		"1234", "12345", "123456", "1234567", "12345678", "123456789", "1234567891", "12345678912", "123456789123",
+	}, CompanyPrefixCandidates("01234567891231"))
+	assert.Len(t, CompanyPrefixAddresses("01234567891231"), MaxCompanyPrefixLength-MinCompanyPrefixLength+1)
+}
+
+func TestAddresses(t *testing.T) {
+	assert.Equal(t, "fa3781", Namespace)
+	assert.Len(t, ProductAddress("00012345600012"), 70)
+	assert.False(t, ProductAddressReserved("00012345600012"))
+	assert.True(t, IsHistoryAddress(HistoryAddress("00012345600012", 1)))
+	assert.Equal(t, HistoryAddress("00012345600012", 1), HistoryAddress("00012345600012", HistoryPageSize))
+	assert.NotEqual(t, HistoryAddress("00012345600012", 1), HistoryAddress("00012345600012", HistoryPageSize+1))
+	assert.True(t, strings.HasPrefix(HistoryAddress("00012345600012", HistoryPageSize+1), HistoryPrefix("00012345600012")))
+	assert.True(t, HistoryAddress("00012345600012", 16*HistoryPageSize) < HistoryAddress("00012345600012", 16*HistoryPageSize+1))
+	assert.False(t, IsHistoryAddress(AgentAddress("02aa")))
+
+	reserved := []string{
+		OrganizationAddress("1234567"), CompanyPrefixAddress("1234567"), AgentAddress("02aa"), HistoryAddress("00012345600012", 1),
+	}
+	for i, address := range reserved {
+		assert.Len(t, address, 70)
+		assert.True(t, IsReserved(address))
+		for _, other := range reserved[:i] {
+			assert.NotEqual(t, other, address)
+		}
+	}
+}
+
+// attributeRunes biases generated keys and values towards the characters
+// with a meaning in the serialized form
+var attributeRunes = []rune("ab=,|\\ \u00e9")
+
+type quickAttributes map[string]string
+
+func (quickAttributes) Generate(r *rand.Rand, size int) reflect.Value {
+	randomString := func() string {
+		runes := make([]rune, r.Intn(size+1))
+		for i := range runes {
+			runes[i] = attributeRunes[r.Intn(len(attributeRunes))]
+		}
+		return string(runes)
+	}
+	attrs := quickAttributes{}
+	for i := r.Intn(size + 1); i > 0; i-- {
+		attrs[randomString()] = randomString()
+	}
+	return reflect.ValueOf(attrs)
+}
+
+func (self quickAttributes) toAttributes() Attributes {
+	attrs := Attributes{}
+	for k, v := range self {
+		attrs[k] = v
+	}
+	return attrs
+}
+
+func TestAttributesSerializeDeterministic(t *testing.T) {
+	deterministic := func(in quickAttributes) bool {
+		expected := in.toAttributes().serialize()
+		for i := 0; i < 10; i++ {
+			// A fresh map gets a fresh iteration order
+			if string(in.toAttributes().serialize()) != string(expected) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(deterministic, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAttributesRoundTrip(t *testing.T) {
+	roundTrip := func(in quickAttributes) bool {
+		attrs := in.toAttributes()
+		out := DeserializeAttributes(SplitAttributes(string(attrs.serialize())))
+		return reflect.DeepEqual(attrs, out)
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAttributesSerialize(t *testing.T) {
+	attrs := Attributes{"weight": "300", "uom": "lbs", "desc": `a,b=c|d\e`}
+	assert.Equal(t, `desc=a\,b\=c\|d\\e,uom=lbs,weight=300`, string(attrs.serialize()))
+	assert.Equal(t, Attributes{}, DeserializeAttributes(SplitAttributes("")))
+	// Entries written without escaping by older clients read as before
+	assert.Equal(t, Attributes{"uom": "cases", "path": `C:\dir`}, DeserializeAttributes([]string{"uom=cases", `path=C:\dir`}))
+}
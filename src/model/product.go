@@ -0,0 +1,126 @@
+// Package model holds the product records of the mdata transaction family:
+// how they are encoded in state and the addresses they are stored at. It is
+// shared by the mdata client and transaction processor so both read state the
+// same way.
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/tross-tyson/mdata_go/src/protobuf/product_pb2"
+)
+
+// Product is a product record stored in state
+type Product struct {
+	Gtin       string     `json:"gtin" yaml:"gtin"`
+	State      string     `json:"state" yaml:"state"`
+	Attributes Attributes `json:"attributes" yaml:"attributes"`
+	// Public key of the signer that created the product
+	Creator string `json:"creator,omitempty" yaml:"creator,omitempty"`
+	// Public keys allowed to change or delete the product
+	Owners []string `json:"owners,omitempty" yaml:"owners,omitempty"`
+	// Version counts the changes made to the product
+	Version uint64 `json:"version" yaml:"version"`
+}
+
+// IsOwner reports whether publicKey is one of the product's owners
+func (self *Product) IsOwner(publicKey string) bool {
+	for _, owner := range self.Owners {
+		if owner == publicKey {
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeProducts reads the products stored at a product address, sorted by
+// GTIN. State written before the ProductContainer encoding is still read; the
+// processor rewrites it as a ProductContainer the next time a product at that
+// address changes.
+func DecodeProducts(data []byte) ([]*Product, error) {
+	if isLegacy(data) {
+		return decodeLegacy(data)
+	}
+
+	container := &product_pb2.ProductContainer{}
+	err := proto.Unmarshal(data, container)
+	if err != nil {
+		return nil, fmt.Errorf("Malformed product data: %v", err)
+	}
+
+	var products []*Product
+	for _, entry := range container.GetEntries() {
+		attributes := Attributes{}
+		for _, attr := range entry.GetAttributes() {
+			attributes[attr.GetKey()] = attr.GetValue()
+		}
+		products = append(products, &Product{
+			Gtin:       entry.GetGtin(),
+			Attributes: attributes,
+			State:      entry.GetState(),
+			Creator:    entry.GetCreator(),
+			Owners:     entry.GetOwners(),
+			Version:    entry.GetVersion(),
+		})
+	}
+	return products, nil
+}
+
+// EncodeProducts encodes the products stored at one address as a
+// ProductContainer. Products, their attributes and their owners are sorted
+// so every node writes the same bytes.
+func EncodeProducts(products []*Product) ([]byte, error) {
+	sorted := append([]*Product{}, products...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Gtin < sorted[j].Gtin })
+
+	container := &product_pb2.ProductContainer{}
+	for _, product := range sorted {
+		owners := append([]string{}, product.Owners...)
+		sort.Strings(owners)
+		entry := &product_pb2.Product{
+			Gtin:    product.Gtin,
+			State:   product.State,
+			Creator: product.Creator,
+			Owners:  owners,
+			Version: product.Version,
+		}
+		for _, k := range product.Attributes.Keys() {
+			entry.Attributes = append(entry.Attributes, &product_pb2.Product_Attribute{
+				Key:   k,
+				Value: product.Attributes[k],
+			})
+		}
+		container.Entries = append(container.Entries, entry)
+	}
+	return proto.Marshal(container)
+}
+
+// isLegacy reports whether data uses the pipe-delimited encoding
+// "gtin,key=value,...,STATE|...". Legacy state starts with the first digit
+// of a GTIN, while a ProductContainer starts with the tag of its entries field.
+func isLegacy(data []byte) bool {
+	return len(data) > 0 && data[0] >= '0' && data[0] <= '9'
+}
+
+func decodeLegacy(data []byte) ([]*Product, error) {
+	var products []*Product
+	for _, str := range strings.Split(string(data), "|") {
+		parts := strings.Split(string(str), ",")
+		if len(parts) < 3 { //Product must have at least three serialized attributes (even if Product.Attributes is empty)
+			return nil, fmt.Errorf("Malformed product data: '%v'", string(data))
+		}
+
+		attrs := parts[1 : len(parts)-1]
+
+		products = append(products, &Product{
+			Gtin:       parts[0],
+			Attributes: DeserializeAttributes(attrs),
+			State:      parts[len(parts)-1],
+		})
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].Gtin < products[j].Gtin })
+	return products, nil
+}
@@ -0,0 +1,6 @@
+package model
+
+// RegistrarsSetting is the Sawtooth setting listing, comma separated, the
+// public keys allowed to create organizations and assign GS1 company
+// prefixes to them
+const RegistrarsSetting = "mdata.org.registrars"
@@ -0,0 +1,8 @@
+// Package protobuf holds the Go code generated from the message definitions in
+// the top level protos directory. Run `go generate` here after editing a .proto.
+package protobuf
+
+//go:generate protoc -I ../../protos --go_out=paths=source_relative:mdata_payload_pb2 ../../protos/mdata_payload.proto
+//go:generate protoc -I ../../protos --go_out=paths=source_relative:product_pb2 ../../protos/product.proto
+//go:generate protoc -I ../../protos --go_out=paths=source_relative:organization_pb2 ../../protos/organization.proto
+//go:generate protoc -I ../../protos --go_out=paths=source_relative:history_pb2 ../../protos/history.proto
@@ -0,0 +1,449 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        v3.7.1
+// source: mdata_payload.proto
+
+package mdata_payload_pb2
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type MdPayload_Action int32
+
+const (
+	MdPayload_ACTION_UNSET     MdPayload_Action = 0
+	MdPayload_CREATE           MdPayload_Action = 1
+	MdPayload_UPDATE           MdPayload_Action = 2
+	MdPayload_DELETE           MdPayload_Action = 3
+	MdPayload_SET              MdPayload_Action = 4
+	MdPayload_PATCH            MdPayload_Action = 5
+	MdPayload_UNSET_ATTRIBUTES MdPayload_Action = 6
+	MdPayload_ORG_CREATE       MdPayload_Action = 7
+	MdPayload_ORG_UPDATE       MdPayload_Action = 8
+	MdPayload_TRANSFER         MdPayload_Action = 9
+	MdPayload_AGENT_CREATE     MdPayload_Action = 10
+	MdPayload_AGENT_UPDATE     MdPayload_Action = 11
+)
+
+// Enum value maps for MdPayload_Action.
+var (
+	MdPayload_Action_name = map[int32]string{
+		0:  "ACTION_UNSET",
+		1:  "CREATE",
+		2:  "UPDATE",
+		3:  "DELETE",
+		4:  "SET",
+		5:  "PATCH",
+		6:  "UNSET_ATTRIBUTES",
+		7:  "ORG_CREATE",
+		8:  "ORG_UPDATE",
+		9:  "TRANSFER",
+		10: "AGENT_CREATE",
+		11: "AGENT_UPDATE",
+	}
+	MdPayload_Action_value = map[string]int32{
+		"ACTION_UNSET":     0,
+		"CREATE":           1,
+		"UPDATE":           2,
+		"DELETE":           3,
+		"SET":              4,
+		"PATCH":            5,
+		"UNSET_ATTRIBUTES": 6,
+		"ORG_CREATE":       7,
+		"ORG_UPDATE":       8,
+		"TRANSFER":         9,
+		"AGENT_CREATE":     10,
+		"AGENT_UPDATE":     11,
+	}
+)
+
+func (x MdPayload_Action) Enum() *MdPayload_Action {
+	p := new(MdPayload_Action)
+	*p = x
+	return p
+}
+
+func (x MdPayload_Action) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MdPayload_Action) Descriptor() protoreflect.EnumDescriptor {
+	return file_mdata_payload_proto_enumTypes[0].Descriptor()
+}
+
+func (MdPayload_Action) Type() protoreflect.EnumType {
+	return &file_mdata_payload_proto_enumTypes[0]
+}
+
+func (x MdPayload_Action) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MdPayload_Action.Descriptor instead.
+func (MdPayload_Action) EnumDescriptor() ([]byte, []int) {
+	return file_mdata_payload_proto_rawDescGZIP(), []int{0, 0}
+}
+
+type MdPayload struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Action          MdPayload_Action       `protobuf:"varint,1,opt,name=action,proto3,enum=MdPayload_Action" json:"action,omitempty"`
+	Gtin            string                 `protobuf:"bytes,2,opt,name=gtin,proto3" json:"gtin,omitempty"`
+	Attributes      []*MdPayload_Attribute `protobuf:"bytes,3,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	State           string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	Timestamp       int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	RemoveKeys      []string               `protobuf:"bytes,6,rep,name=remove_keys,json=removeKeys,proto3" json:"remove_keys,omitempty"`
+	OrgId           string                 `protobuf:"bytes,7,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
+	OrgName         string                 `protobuf:"bytes,8,opt,name=org_name,json=orgName,proto3" json:"org_name,omitempty"`
+	Admins          []string               `protobuf:"bytes,9,rep,name=admins,proto3" json:"admins,omitempty"`
+	CompanyPrefixes []string               `protobuf:"bytes,10,rep,name=company_prefixes,json=companyPrefixes,proto3" json:"company_prefixes,omitempty"`
+	NewOwner        string                 `protobuf:"bytes,11,opt,name=new_owner,json=newOwner,proto3" json:"new_owner,omitempty"`
+	PublicKey       string                 `protobuf:"bytes,12,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Roles           []string               `protobuf:"bytes,13,rep,name=roles,proto3" json:"roles,omitempty"`
+	ExpectedVersion uint64                 `protobuf:"varint,14,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	CheckVersion    bool                   `protobuf:"varint,15,opt,name=check_version,json=checkVersion,proto3" json:"check_version,omitempty"`
+}
+
+func (x *MdPayload) Reset() {
+	*x = MdPayload{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mdata_payload_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MdPayload) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MdPayload) ProtoMessage() {}
+
+func (x *MdPayload) ProtoReflect() protoreflect.Message {
+	mi := &file_mdata_payload_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MdPayload.ProtoReflect.Descriptor instead.
+func (*MdPayload) Descriptor() ([]byte, []int) {
+	return file_mdata_payload_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MdPayload) GetAction() MdPayload_Action {
+	if x != nil {
+		return x.Action
+	}
+	return MdPayload_ACTION_UNSET
+}
+
+func (x *MdPayload) GetGtin() string {
+	if x != nil {
+		return x.Gtin
+	}
+	return ""
+}
+
+func (x *MdPayload) GetAttributes() []*MdPayload_Attribute {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *MdPayload) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *MdPayload) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *MdPayload) GetRemoveKeys() []string {
+	if x != nil {
+		return x.RemoveKeys
+	}
+	return nil
+}
+
+func (x *MdPayload) GetOrgId() string {
+	if x != nil {
+		return x.OrgId
+	}
+	return ""
+}
+
+func (x *MdPayload) GetOrgName() string {
+	if x != nil {
+		return x.OrgName
+	}
+	return ""
+}
+
+func (x *MdPayload) GetAdmins() []string {
+	if x != nil {
+		return x.Admins
+	}
+	return nil
+}
+
+func (x *MdPayload) GetCompanyPrefixes() []string {
+	if x != nil {
+		return x.CompanyPrefixes
+	}
+	return nil
+}
+
+func (x *MdPayload) GetNewOwner() string {
+	if x != nil {
+		return x.NewOwner
+	}
+	return ""
+}
+
+func (x *MdPayload) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *MdPayload) GetRoles() []string {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+func (x *MdPayload) GetExpectedVersion() uint64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+func (x *MdPayload) GetCheckVersion() bool {
+	if x != nil {
+		return x.CheckVersion
+	}
+	return false
+}
+
+type MdPayload_Attribute struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *MdPayload_Attribute) Reset() {
+	*x = MdPayload_Attribute{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mdata_payload_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MdPayload_Attribute) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MdPayload_Attribute) ProtoMessage() {}
+
+func (x *MdPayload_Attribute) ProtoReflect() protoreflect.Message {
+	mi := &file_mdata_payload_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MdPayload_Attribute.ProtoReflect.Descriptor instead.
+func (*MdPayload_Attribute) Descriptor() ([]byte, []int) {
+	return file_mdata_payload_proto_rawDescGZIP(), []int{0, 0}
+}
+
+func (x *MdPayload_Attribute) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *MdPayload_Attribute) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+var File_mdata_payload_proto protoreflect.FileDescriptor
+
+var file_mdata_payload_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x6d, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xde, 0x05, 0x0a, 0x09, 0x4d, 0x64, 0x50, 0x61, 0x79, 0x6c,
+	0x6f, 0x61, 0x64, 0x12, 0x29, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x4d, 0x64, 0x50, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x2e,
+	0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12,
+	0x0a, 0x04, 0x67, 0x74, 0x69, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x67, 0x74,
+	0x69, 0x6e, 0x12, 0x34, 0x0a, 0x0a, 0x61, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x65, 0x73,
+	0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x4d, 0x64, 0x50, 0x61, 0x79, 0x6c, 0x6f,
+	0x61, 0x64, 0x2e, 0x41, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x65, 0x52, 0x0a, 0x61, 0x74,
+	0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1c,
+	0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1f, 0x0a, 0x0b,
+	0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0a, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x4b, 0x65, 0x79, 0x73, 0x12, 0x15, 0x0a,
+	0x06, 0x6f, 0x72, 0x67, 0x5f, 0x69, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6f,
+	0x72, 0x67, 0x49, 0x64, 0x12, 0x19, 0x0a, 0x08, 0x6f, 0x72, 0x67, 0x5f, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x72, 0x67, 0x4e, 0x61, 0x6d, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x73, 0x18, 0x09, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x06, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x73, 0x12, 0x29, 0x0a, 0x10, 0x63, 0x6f, 0x6d, 0x70, 0x61,
+	0x6e, 0x79, 0x5f, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x65, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0f, 0x63, 0x6f, 0x6d, 0x70, 0x61, 0x6e, 0x79, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78,
+	0x65, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x6e, 0x65, 0x77, 0x5f, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x18,
+	0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6e, 0x65, 0x77, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x12,
+	0x1d, 0x0a, 0x0a, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x0c, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x14,
+	0x0a, 0x05, 0x72, 0x6f, 0x6c, 0x65, 0x73, 0x18, 0x0d, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x72,
+	0x6f, 0x6c, 0x65, 0x73, 0x12, 0x29, 0x0a, 0x10, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64,
+	0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f,
+	0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12,
+	0x23, 0x0a, 0x0d, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x18, 0x0f, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x56, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x33, 0x0a, 0x09, 0x41, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74,
+	0x65, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0xba, 0x01, 0x0a, 0x06, 0x41, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x0c, 0x41, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x55,
+	0x4e, 0x53, 0x45, 0x54, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x43, 0x52, 0x45, 0x41, 0x54, 0x45,
+	0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x55, 0x50, 0x44, 0x41, 0x54, 0x45, 0x10, 0x02, 0x12, 0x0a,
+	0x0a, 0x06, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45, 0x10, 0x03, 0x12, 0x07, 0x0a, 0x03, 0x53, 0x45,
+	0x54, 0x10, 0x04, 0x12, 0x09, 0x0a, 0x05, 0x50, 0x41, 0x54, 0x43, 0x48, 0x10, 0x05, 0x12, 0x14,
+	0x0a, 0x10, 0x55, 0x4e, 0x53, 0x45, 0x54, 0x5f, 0x41, 0x54, 0x54, 0x52, 0x49, 0x42, 0x55, 0x54,
+	0x45, 0x53, 0x10, 0x06, 0x12, 0x0e, 0x0a, 0x0a, 0x4f, 0x52, 0x47, 0x5f, 0x43, 0x52, 0x45, 0x41,
+	0x54, 0x45, 0x10, 0x07, 0x12, 0x0e, 0x0a, 0x0a, 0x4f, 0x52, 0x47, 0x5f, 0x55, 0x50, 0x44, 0x41,
+	0x54, 0x45, 0x10, 0x08, 0x12, 0x0c, 0x0a, 0x08, 0x54, 0x52, 0x41, 0x4e, 0x53, 0x46, 0x45, 0x52,
+	0x10, 0x09, 0x12, 0x10, 0x0a, 0x0c, 0x41, 0x47, 0x45, 0x4e, 0x54, 0x5f, 0x43, 0x52, 0x45, 0x41,
+	0x54, 0x45, 0x10, 0x0a, 0x12, 0x10, 0x0a, 0x0c, 0x41, 0x47, 0x45, 0x4e, 0x54, 0x5f, 0x55, 0x50,
+	0x44, 0x41, 0x54, 0x45, 0x10, 0x0b, 0x42, 0x40, 0x5a, 0x3e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x72, 0x6f, 0x73, 0x73, 0x2d, 0x74, 0x79, 0x73, 0x6f, 0x6e,
+	0x2f, 0x6d, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x67, 0x6f, 0x2f, 0x73, 0x72, 0x63, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x6d, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x70, 0x61, 0x79,
+	0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x70, 0x62, 0x32, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_mdata_payload_proto_rawDescOnce sync.Once
+	file_mdata_payload_proto_rawDescData = file_mdata_payload_proto_rawDesc
+)
+
+func file_mdata_payload_proto_rawDescGZIP() []byte {
+	file_mdata_payload_proto_rawDescOnce.Do(func() {
+		file_mdata_payload_proto_rawDescData = protoimpl.X.CompressGZIP(file_mdata_payload_proto_rawDescData)
+	})
+	return file_mdata_payload_proto_rawDescData
+}
+
+var file_mdata_payload_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_mdata_payload_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_mdata_payload_proto_goTypes = []interface{}{
+	(MdPayload_Action)(0),       // 0: MdPayload.Action
+	(*MdPayload)(nil),           // 1: MdPayload
+	(*MdPayload_Attribute)(nil), // 2: MdPayload.Attribute
+}
+var file_mdata_payload_proto_depIdxs = []int32{
+	0, // 0: MdPayload.action:type_name -> MdPayload.Action
+	2, // 1: MdPayload.attributes:type_name -> MdPayload.Attribute
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_mdata_payload_proto_init() }
+func file_mdata_payload_proto_init() {
+	if File_mdata_payload_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_mdata_payload_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MdPayload); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mdata_payload_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MdPayload_Attribute); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_mdata_payload_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_mdata_payload_proto_goTypes,
+		DependencyIndexes: file_mdata_payload_proto_depIdxs,
+		EnumInfos:         file_mdata_payload_proto_enumTypes,
+		MessageInfos:      file_mdata_payload_proto_msgTypes,
+	}.Build()
+	File_mdata_payload_proto = out.File
+	file_mdata_payload_proto_rawDesc = nil
+	file_mdata_payload_proto_goTypes = nil
+	file_mdata_payload_proto_depIdxs = nil
+}